@@ -1,23 +1,130 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/nkrus/gitlab-flagman/config"
 	"github.com/nkrus/gitlab-flagman/internal/args"
+	"github.com/nkrus/gitlab-flagman/internal/backend"
 	"github.com/nkrus/gitlab-flagman/internal/client"
 	"github.com/nkrus/gitlab-flagman/internal/service"
 )
 
+// exitCodeDrift код выхода команды drift, если флаги в GitLab отличаются от конфигурации
+const exitCodeDrift = 2
+
 func main() {
 	parsedArgs, err := args.ParseArgs()
 	if err != nil {
 		log.Fatalf("Error parsing arguments: %v", err)
 	}
 
-	featureFlags, err := config.ReadFlagsFromYAML(parsedArgs.FlagsFile)
+	// Файл флагов читается и проверяется до первого запроса к GitLab
+	var cfg *config.Config
+	switch {
+	case parsedArgs.Command == args.CommandApply:
+	case parsedArgs.Command == args.CommandAdopt && len(parsedArgs.FlagNames) > 0:
+	default:
+		cfg = readConfig(parsedArgs.FlagsFiles)
+	}
+	if parsedArgs.Command == args.CommandValidate {
+		log.Printf("%s: %d flags and %d user lists are valid", strings.Join(parsedArgs.FlagsFiles, ", "), len(cfg.Flags), len(cfg.UserLists))
+		return
+	}
+
+	featureFlagService := service.FeatureFlagService{
+		Backend:        newBackend(parsedArgs),
+		DryRun:         parsedArgs.DryRun,
+		Prune:          parsedArgs.Prune,
+		PruneUserLists: parsedArgs.PruneUserLists,
+		Force:          parsedArgs.Force,
+		PlanOut:        parsedArgs.PlanOut,
+		Selection: service.Selection{
+			Only:    parsedArgs.Only,
+			Exclude: parsedArgs.Exclude,
+			Tags:    parsedArgs.Tags,
+		},
+
+		MaxDeletes:        parsedArgs.DeletionLimit,
+		ContinueOnError:   parsedArgs.ContinueOnError,
+		RollbackOnFailure: parsedArgs.RollbackOnFailure,
+	}
+	if cfg != nil {
+		featureFlagService.UserLists = cfg.UserLists
+	}
+
+	switch parsedArgs.Command {
+	case args.CommandAdopt:
+		var featureFlags []config.FeatureFlag
+		if cfg != nil {
+			featureFlags = cfg.Flags
+		}
+		if err := featureFlagService.AdoptFeatureFlags(parsedArgs.FlagNames, featureFlags); err != nil {
+			log.Fatalf("Error adopting feature flags: %v", err)
+		}
+	case args.CommandDrift:
+		report, err := featureFlagService.DetectDrift(cfg.Flags)
+		if err != nil {
+			log.Fatalf("Error detecting drift: %v", err)
+		}
+		if parsedArgs.DriftReport != "" {
+			if err := service.WriteDriftReport(parsedArgs.DriftReport, report); err != nil {
+				log.Fatalf("Error saving drift report: %v", err)
+			}
+		}
+		if report.HasDrift() {
+			os.Exit(exitCodeDrift)
+		}
+	case args.CommandApply:
+		if err := featureFlagService.ApplyPlanFile(parsedArgs.PlanFile); err != nil {
+			log.Fatalf("Error applying plan %q: %v", parsedArgs.PlanFile, err)
+		}
+	default:
+		if err := featureFlagService.SyncFeatureFlags(cfg.Flags); err != nil {
+			log.Fatalf("Error syncing feature flags: %v", err)
+		}
+	}
+}
+
+// readConfig читает файлы флагов и проверяет их по правилам GitLab, завершая работу при ошибках
+func readConfig(flagsFiles []string) *config.Config {
+	cfg, err := config.ReadConfigFromYAML(flagsFiles...)
 	if err != nil {
-		log.Fatalf("Error reading feature flags from file %q: %v", parsedArgs.FlagsFile, err)
+		log.Fatalf("Error reading feature flags from %q: %v", flagsFiles, err)
+	}
+	for _, warning := range cfg.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	if err := config.Validate(cfg); err != nil {
+		log.Fatalf("Invalid feature flags in %q: %v", flagsFiles, err)
+	}
+	return cfg
+}
+
+// newBackend создаёт клиент GitLab или, если указан -stateFile, локальное хранилище флагов
+func newBackend(parsedArgs *args.Args) service.FeatureFlagBackend {
+	if parsedArgs.StateFile != "" {
+		log.Printf("Using local state file %s instead of GitLab", parsedArgs.StateFile)
+		return backend.NewFile(parsedArgs.StateFile)
+	}
+
+	authenticator, err := client.NewAuthenticator(parsedArgs.GitLabAuth, parsedArgs.GitLabToken)
+	if err != nil {
+		log.Fatalf("Error configuring GitLab authentication: %v", err)
+	}
+
+	transport, err := client.NewTransport(client.TransportConfig{
+		CAFile:             parsedArgs.GitLabCACert,
+		CertFile:           parsedArgs.GitLabClientCert,
+		KeyFile:            parsedArgs.GitLabClientKey,
+		ProxyURL:           parsedArgs.GitLabProxy,
+		InsecureSkipVerify: parsedArgs.GitLabInsecure,
+	})
+	if err != nil {
+		log.Fatalf("Error configuring GitLab connection: %v", err)
 	}
 
 	gitLabClient := client.NewGitLabClient(
@@ -25,10 +132,21 @@ func main() {
 		parsedArgs.GitLabToken,
 		parsedArgs.GitLabProjectID,
 		parsedArgs.GitLabRequestTimeout,
+		client.WithRetryPolicy(client.RetryPolicy{
+			MaxRetries: parsedArgs.GitLabRetries,
+			BaseDelay:  parsedArgs.GitLabRetryDelay,
+			MaxDelay:   parsedArgs.GitLabRetryMaxDelay,
+		}),
+		client.WithRateLimit(parsedArgs.GitLabRateLimit, parsedArgs.GitLabRateBurst),
+		client.WithPageSize(parsedArgs.GitLabPageSize),
+		client.WithAuthenticator(authenticator),
+		client.WithTransport(transport),
 	)
 
-	featureFlagService := service.FeatureFlagService{GitLabClient: gitLabClient}
-	if err := featureFlagService.SyncFeatureFlags(featureFlags); err != nil {
-		log.Fatalf("Error syncing feature flags: %v", err)
+	project, err := gitLabClient.ResolveProject(context.Background())
+	if err != nil {
+		log.Fatalf("Error checking GitLab project: %v", err)
 	}
+	log.Printf("Using GitLab project %s (ID %d)", project.PathWithNamespace, project.ID)
+	return gitLabClient
 }
@@ -2,51 +2,199 @@ package config
 
 import (
 	"fmt"
-	"io"
-	"os"
-	"strings"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
 )
 
 // FeatureFlag структура для чтения флагов из файла
 type FeatureFlag struct {
-	Name        string `yaml:"name" json:"name"`
-	Description string `yaml:"description" json:"description"`
-	Active      bool   `yaml:"active" json:"active"`
-	Strategies  []struct {
-		Name       string                 `yaml:"name" json:"name"`
-		Parameters map[string]interface{} `yaml:"parameters" json:"parameters"`
-		Scopes     []struct {
-			Environment string `yaml:"environment_scope" json:"environment_scope"`
-		} `yaml:"scopes" json:"scopes"`
-	} `yaml:"strategies" json:"strategies"`
+	Name        string     `yaml:"name" json:"name"`
+	Description string     `yaml:"description" json:"description"`
+	Active      bool       `yaml:"active" json:"active"`
+	Strategies  []Strategy `yaml:"strategies" json:"strategies"`
+	// Tags метки для выборочной синхронизации, в GitLab не передаются
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Source место объявления флага в файле, пустое для флагов не из файла
+	Source Position `yaml:"-" json:"-"`
 }
 
-func ReadFlagsFromYAML(fileName string) ([]FeatureFlag, error) {
+// ManagedMarker добавляется в конец описания флагов, созданных gitlab-flagman.
+// Флаги без метки считаются созданными вручную и не изменяются и не удаляются.
+const ManagedMarker = "[managed by gitlab-flagman]"
 
-	if !strings.HasSuffix(fileName, ".yaml") {
-		return nil, fmt.Errorf("flags file must have .yaml extension")
+// Position место в файле флагов
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return ""
 	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Strategy стратегия включения флага
+type Strategy struct {
+	Name       string                 `yaml:"name" json:"name"`
+	Parameters map[string]interface{} `yaml:"parameters" json:"parameters"`
+	Scopes     []Scope                `yaml:"scopes" json:"scopes"`
+	// UserList имя списка пользователей для стратегии gitlabUserList
+	UserList string `yaml:"user_list,omitempty" json:"user_list,omitempty"`
+}
+
+// Scope окружение, в котором действует стратегия
+type Scope struct {
+	Environment string `yaml:"environment_scope" json:"environment_scope"`
+	// Active nil или true - окружение включено. В GitLab такого поля нет,
+	// выключенные окружения убираются из стратегии при чтении файла, см. CompileScopes
+	Active *bool `yaml:"active,omitempty" json:"-"`
+}
+
+// UserList список пользователей GitLab, на который ссылаются стратегии gitlabUserList
+type UserList struct {
+	Name     string   `yaml:"name" json:"name"`
+	UserXIDs []string `yaml:"user_xids" json:"user_xids"`
+	// Source место объявления списка в файле
+	Source Position `yaml:"-" json:"-"`
+}
 
-	// Open the file
-	yamlFile, err := os.Open(fileName)
+// Config содержимое файлов флагов.
+// Каждый документ может быть списком флагов или словарём с ключами flags и userLists.
+type Config struct {
+	Flags []FeatureFlag `yaml:"flags"`
+	// UserLists nil, если ни в одном файле нет раздела userLists и списки пользователей не управляются
+	UserLists []UserList `yaml:"userLists"`
+	// Warnings настройки файла, которые не удалось точно перенести в GitLab
+	Warnings []string `yaml:"-"`
+}
+
+func ReadFlagsFromYAML(paths ...string) ([]FeatureFlag, error) {
+	cfg, err := ReadConfigFromYAML(paths...)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file: %w", err)
+		return nil, err
 	}
-	defer yamlFile.Close()
+	return cfg.Flags, nil
+}
 
-	// Read the file contents
-	fileContent, err := io.ReadAll(yamlFile)
+// ReadConfigFromYAML читает и объединяет флаги из файлов, каталогов и glob шаблонов.
+// Поддерживаются файлы .yaml, .yml и .json, путь "-" читает стандартный ввод.
+// YAML файл может содержать несколько документов. Ошибки всех файлов собираются вместе,
+// флаг или список, объявленный дважды, считается ошибкой с указанием обоих мест.
+func ReadConfigFromYAML(paths ...string) (*Config, error) {
+	fileNames, err := expandPaths(paths)
 	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		return nil, err
 	}
 
-	// Parse the YAML
-	var featureFlags []FeatureFlag
-	if err := yaml.Unmarshal(fileContent, &featureFlags); err != nil {
-		return nil, fmt.Errorf("error unmarshalling YAML: %w", err)
+	var cfg Config
+	var errs DecodeErrors
+	for _, fileName := range fileNames {
+		documents, err := readDocuments(fileName)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range documents {
+			errs = append(errs, cfg.decodeDocument(sourceName(fileName), document)...)
+		}
+	}
+	if len(errs) == 0 {
+		errs = cfg.checkDuplicates()
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("error unmarshalling YAML: %w", errs)
 	}
+	return &cfg, nil
+}
+
+// decodeDocument проверяет документ и добавляет его флаги и списки пользователей в cfg
+func (cfg *Config) decodeDocument(fileName string, root *yaml.Node) DecodeErrors {
+	var document Config
+	target := interface{}(&document)
+	if root.Kind == yaml.SequenceNode {
+		target = &document.Flags
+	}
+
+	// Неизвестные ключи yaml.v3 молча пропускает, поэтому сначала проверяем дерево целиком
+	c := checker{file: fileName}
+	c.check(root, reflect.TypeOf(target).Elem(), "")
+	if len(c.errors) > 0 {
+		return c.errors
+	}
+	if err := root.Decode(target); err != nil {
+		c.report(root, "", "%v", err)
+		return c.errors
+	}
+
+	flagNodes, listNodes := root.Content, []*yaml.Node(nil)
+	if root.Kind == yaml.MappingNode {
+		flagNodes, listNodes = sectionItems(root, "flags"), sectionItems(root, "userLists")
+	}
+	if document.UserLists != nil && cfg.UserLists == nil {
+		cfg.UserLists = []UserList{}
+	}
+	for i, list := range document.UserLists {
+		list.Source = position(fileName, listNodes[i])
+		cfg.UserLists = append(cfg.UserLists, list)
+	}
+	for i, flag := range document.Flags {
+		flag.Source = position(fileName, flagNodes[i])
+		compiled, warnings := CompileScopes(flag)
+		cfg.Flags = append(cfg.Flags, compiled)
+		cfg.Warnings = append(cfg.Warnings, warnings...)
+	}
+	return nil
+}
+
+// checkDuplicates находит флаги и списки пользователей, объявленные больше одного раза
+func (cfg *Config) checkDuplicates() DecodeErrors {
+	var errs DecodeErrors
+	duplicate := func(source, first Position, owner string) {
+		errs = append(errs, DecodeError{
+			File:    source.File,
+			Line:    source.Line,
+			Column:  source.Column,
+			Owner:   owner,
+			Message: "defined more than once, first at " + first.String(),
+		})
+	}
+
+	lists := make(map[string]Position, len(cfg.UserLists))
+	for _, list := range cfg.UserLists {
+		if first, exists := lists[list.Name]; exists {
+			duplicate(list.Source, first, "user list "+list.Name)
+			continue
+		}
+		lists[list.Name] = list.Source
+	}
+	flags := make(map[string]Position, len(cfg.Flags))
+	for _, flag := range cfg.Flags {
+		if first, exists := flags[flag.Name]; exists {
+			duplicate(flag.Source, first, "flag "+flag.Name)
+			continue
+		}
+		flags[flag.Name] = flag.Source
+	}
+	return errs
+}
+
+// sectionItems элементы списка по ключу key словаря node
+func sectionItems(node *yaml.Node, key string) []*yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			if value.Kind == yaml.AliasNode {
+				value = value.Alias
+			}
+			return value.Content
+		}
+	}
+	return nil
+}
 
-	return featureFlags, nil
+func position(fileName string, node *yaml.Node) Position {
+	return Position{File: fileName, Line: node.Line, Column: node.Column}
 }
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReadFlagsFromYAML(t *testing.T) {
@@ -18,6 +22,7 @@ func TestReadFlagsFromYAML(t *testing.T) {
       parameters: {}
       scopes:
         - environment_scope: "TEST"
+  tags: ["payments", "ui"]
 `
 		tmpFile, err := os.CreateTemp("", "feature_flags_*.yaml")
 		assert.NoError(t, err)
@@ -37,6 +42,7 @@ func TestReadFlagsFromYAML(t *testing.T) {
 		assert.Len(t, flags[0].Strategies, 1)
 		assert.Equal(t, "default", flags[0].Strategies[0].Name)
 		assert.Equal(t, "TEST", flags[0].Strategies[0].Scopes[0].Environment)
+		assert.Equal(t, []string{"payments", "ui"}, flags[0].Tags)
 	})
 
 	t.Run("file with invalid extension", func(t *testing.T) {
@@ -52,7 +58,7 @@ func TestReadFlagsFromYAML(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, flags)
-		assert.Equal(t, "flags file must have .yaml extension", err.Error())
+		assert.Equal(t, "flags file must have .yaml, .yml or .json extension", err.Error())
 	})
 
 	t.Run("file not found", func(t *testing.T) {
@@ -89,3 +95,253 @@ func TestReadFlagsFromYAML(t *testing.T) {
 		assert.Contains(t, err.Error(), "error unmarshalling YAML")
 	})
 }
+
+func TestReadConfigFromYAML(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		tmpFile, err := os.CreateTemp(t.TempDir(), "feature_flags_*.yaml")
+		assert.NoError(t, err)
+		_, err = tmpFile.WriteString(content)
+		assert.NoError(t, err)
+		tmpFile.Close()
+		return tmpFile.Name()
+	}
+
+	t.Run("flags and user lists", func(t *testing.T) {
+		fileName := write(t, `
+userLists:
+  - name: beta_testers
+    user_xids: ["alice", "bob"]
+flags:
+  - name: "Feature1"
+    active: true
+    strategies:
+      - name: "gitlabUserList"
+        user_list: beta_testers
+        scopes:
+          - environment_scope: "*"
+`)
+		cfg, err := ReadConfigFromYAML(fileName)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []UserList{{
+			Name:     "beta_testers",
+			UserXIDs: []string{"alice", "bob"},
+			Source:   Position{File: fileName, Line: 3, Column: 5},
+		}}, cfg.UserLists)
+		assert.Len(t, cfg.Flags, 1)
+		assert.Equal(t, "beta_testers", cfg.Flags[0].Strategies[0].UserList)
+		assert.Equal(t, Position{File: fileName, Line: 6, Column: 5}, cfg.Flags[0].Source)
+	})
+
+	t.Run("list of flags does not manage user lists", func(t *testing.T) {
+		cfg, err := ReadConfigFromYAML(write(t, `
+- name: "Feature1"
+  active: true
+`))
+
+		assert.NoError(t, err)
+		assert.Len(t, cfg.Flags, 1)
+		assert.Nil(t, cfg.UserLists)
+	})
+
+	t.Run("inactive scopes are compiled out", func(t *testing.T) {
+		cfg, err := ReadConfigFromYAML(write(t, `
+- name: "Feature1"
+  active: true
+  strategies:
+    - name: "default"
+      scopes:
+        - environment_scope: PROD
+          active: false
+`))
+
+		assert.NoError(t, err)
+		assert.False(t, cfg.Flags[0].Active)
+		assert.Empty(t, cfg.Flags[0].Strategies)
+		assert.Equal(t, []string{"flag Feature1: all scopes are inactive, the flag will be deactivated"}, cfg.Warnings)
+	})
+
+	t.Run("empty user lists section", func(t *testing.T) {
+		cfg, err := ReadConfigFromYAML(write(t, `
+userLists: []
+flags: []
+`))
+
+		assert.NoError(t, err)
+		assert.NotNil(t, cfg.UserLists)
+		assert.Empty(t, cfg.UserLists)
+	})
+}
+
+func TestReadConfigFromYAMLStrict(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "feature_flags_*.yaml")
+	assert.NoError(t, err)
+	_, err = tmpFile.WriteString(`- name: typo
+  stratgies: []
+  active: maybe
+- name: scopes
+  strategies:
+    - name: default
+      parameters: [x]
+      scopes:
+        - environment_scope: TEST
+          enabled: true
+- description: no name
+  tags: payments
+`)
+	assert.NoError(t, err)
+	tmpFile.Close()
+
+	cfg, err := ReadConfigFromYAML(tmpFile.Name())
+
+	assert.Nil(t, cfg)
+	assert.ErrorContains(t, err, "error unmarshalling YAML: 5 errors:")
+	var decodeErrs DecodeErrors
+	assert.True(t, errors.As(err, &decodeErrs))
+	assert.Equal(t, DecodeErrors{
+		{File: tmpFile.Name(), Line: 2, Column: 3, Owner: "flag typo", Message: `unknown field "stratgies" (expected one of: name, description, active, strategies, tags)`},
+		{File: tmpFile.Name(), Line: 3, Column: 11, Owner: "flag typo", Message: `cannot use "maybe" as a bool`},
+		{File: tmpFile.Name(), Line: 7, Column: 19, Owner: "flag scopes", Message: "expected a mapping, got a list"},
+		{File: tmpFile.Name(), Line: 10, Column: 11, Owner: "flag scopes", Message: `unknown field "enabled" (expected one of: environment_scope, active)`},
+		{File: tmpFile.Name(), Line: 12, Column: 9, Owner: "flag at line 11", Message: `expected a list, got "payments"`},
+	}, decodeErrs)
+	assert.Contains(t, err.Error(), tmpFile.Name()+`:2:3: flag typo: unknown field "stratgies"`)
+}
+
+func TestReadConfigFromMultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	payments := writeFile("flags/payments.yaml", `
+- name: checkout
+  active: true
+---
+userLists:
+  - name: beta
+    user_xids: ["alice"]
+flags:
+  - name: refunds
+`)
+	writeFile("flags/ui/new_ui.yaml", `
+- name: new_ui
+`)
+	writeFile("flags/README.md", "not a flags file")
+	legacy := writeFile("legacy.yaml", `
+- name: legacy
+`)
+
+	names := func(cfg *Config) []string {
+		var result []string
+		for _, flag := range cfg.Flags {
+			result = append(result, flag.Name)
+		}
+		return result
+	}
+
+	t.Run("directory and file", func(t *testing.T) {
+		cfg, err := ReadConfigFromYAML(filepath.Join(dir, "flags"), legacy)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"checkout", "refunds", "new_ui", "legacy"}, names(cfg))
+		require.Len(t, cfg.UserLists, 1)
+		assert.Equal(t, "beta", cfg.UserLists[0].Name)
+		assert.Equal(t, Position{File: payments, Line: 9, Column: 5}, cfg.Flags[1].Source)
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		cfg, err := ReadConfigFromYAML(filepath.Join(dir, "*.yaml"))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"legacy"}, names(cfg))
+		assert.Nil(t, cfg.UserLists)
+	})
+
+	t.Run("same file twice is read once", func(t *testing.T) {
+		cfg, err := ReadConfigFromYAML(legacy, filepath.Join(dir, "*.yaml"))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"legacy"}, names(cfg))
+	})
+
+	t.Run("glob without matches", func(t *testing.T) {
+		_, err := ReadConfigFromYAML(filepath.Join(dir, "missing_*.yaml"))
+
+		assert.ErrorContains(t, err, "no flags files match")
+	})
+
+	t.Run("duplicate flag across files", func(t *testing.T) {
+		duplicate := writeFile("duplicate/checkout.yaml", `
+- name: checkout
+`)
+
+		_, err := ReadConfigFromYAML(filepath.Join(dir, "flags"), duplicate)
+
+		assert.EqualError(t, err, "error unmarshalling YAML: 1 errors:\n\t"+
+			duplicate+":2:3: flag checkout: defined more than once, first at "+payments+":2:3")
+	})
+}
+
+func TestReadConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("yml and json files", func(t *testing.T) {
+		yml := writeFile("a.yml", "- name: from_yml\n")
+		jsonFile := writeFile("b.json", "{\n\t\"userLists\": [{\"name\": \"beta\", \"user_xids\": [\"alice\"]}],\n\t\"flags\": [\n\t\t{\"name\": \"from_json\", \"active\": true}\n\t]\n}\n")
+
+		cfg, err := ReadConfigFromYAML(yml, jsonFile)
+
+		require.NoError(t, err)
+		require.Len(t, cfg.Flags, 2)
+		assert.Equal(t, "from_yml", cfg.Flags[0].Name)
+		assert.Equal(t, "from_json", cfg.Flags[1].Name)
+		assert.True(t, cfg.Flags[1].Active)
+		assert.Equal(t, Position{File: jsonFile, Line: 4, Column: 3}, cfg.Flags[1].Source)
+		require.Len(t, cfg.UserLists, 1)
+		assert.Equal(t, []string{"alice"}, cfg.UserLists[0].UserXIDs)
+	})
+
+	t.Run("json is checked strictly", func(t *testing.T) {
+		jsonFile := writeFile("strict.json", `[{"name": "flag1", "stratgies": []}]`)
+
+		_, err := ReadConfigFromYAML(jsonFile)
+
+		assert.ErrorContains(t, err, jsonFile+`:1:20: flag flag1: unknown field "stratgies"`)
+	})
+
+	t.Run("json syntax error", func(t *testing.T) {
+		jsonFile := writeFile("broken.json", "[\n  {\"name\": \"flag1\",}\n]\n")
+
+		_, err := ReadConfigFromYAML(jsonFile)
+
+		assert.ErrorContains(t, err, "error unmarshalling JSON: "+jsonFile+":2:20: invalid character '}'")
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"yaml": "- name: piped\n  active: true\n",
+			"json": `[{"name": "piped", "active": true}]`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				stdin = strings.NewReader(content)
+				t.Cleanup(func() { stdin = os.Stdin })
+
+				cfg, err := ReadConfigFromYAML("-")
+
+				require.NoError(t, err)
+				require.Len(t, cfg.Flags, 1)
+				assert.Equal(t, "piped", cfg.Flags[0].Name)
+				assert.True(t, cfg.Flags[0].Active)
+				assert.Equal(t, "<stdin>", cfg.Flags[0].Source.File)
+			})
+		}
+	})
+}
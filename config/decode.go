@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeError ошибка в файле флагов вместе с её положением
+type DecodeError struct {
+	File   string
+	Line   int
+	Column int
+	// Owner флаг или список пользователей, к которому относится ошибка, если он известен
+	Owner   string
+	Message string
+}
+
+func (e DecodeError) Error() string {
+	if e.Owner == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Owner, e.Message)
+}
+
+// DecodeErrors все ошибки, найденные в файле флагов
+type DecodeErrors []DecodeError
+
+func (e DecodeErrors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%d errors:", len(e)))
+	for _, err := range e {
+		lines = append(lines, "\t"+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	featureFlagType = reflect.TypeOf(FeatureFlag{})
+	userListType    = reflect.TypeOf(UserList{})
+)
+
+// checker проверяет дерево YAML по структурам конфигурации до декодирования:
+// неизвестные ключи и значения не того вида собираются все сразу, с позицией в файле
+type checker struct {
+	file   string
+	errors DecodeErrors
+}
+
+func (c *checker) report(node *yaml.Node, owner, format string, args ...interface{}) {
+	c.errors = append(c.errors, DecodeError{
+		File:    c.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Owner:   owner,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) check(node *yaml.Node, t reflect.Type, owner string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		c.check(node, t.Elem(), owner)
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			c.report(node, owner, "expected a mapping, got %s", describe(node))
+			return
+		}
+		switch t {
+		case featureFlagType:
+			owner = ownerName(node, "flag")
+		case userListType:
+			owner = ownerName(node, "user list")
+		}
+		fields := yamlFields(t)
+		names := make([]string, 0, len(fields))
+		types := make(map[string]reflect.Type, len(fields))
+		for _, field := range fields {
+			names = append(names, field.name)
+			types[field.name] = field.typ
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := types[key.Value]
+			if !ok {
+				c.report(key, owner, "unknown field %q (expected one of: %s)", key.Value, strings.Join(names, ", "))
+				continue
+			}
+			c.check(value, fieldType, owner)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			c.report(node, owner, "expected a list, got %s", describe(node))
+			return
+		}
+		for _, item := range node.Content {
+			c.check(item, t.Elem(), owner)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			c.report(node, owner, "expected a mapping, got %s", describe(node))
+		}
+	case reflect.Interface:
+	default:
+		if node.Kind != yaml.ScalarNode {
+			c.report(node, owner, "expected a %s, got %s", t.Kind(), describe(node))
+			return
+		}
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.report(node, owner, "cannot use %q as a %s", node.Value, t.Kind())
+		}
+	}
+}
+
+// ownerName описание флага или списка для сообщений об ошибках: по имени, а без него - по строке
+func ownerName(node *yaml.Node, kind string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "name" && node.Content[i+1].Kind == yaml.ScalarNode && node.Content[i+1].Value != "" {
+			return kind + " " + node.Content[i+1].Value
+		}
+	}
+	return fmt.Sprintf("%s at line %d", kind, node.Line)
+}
+
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("%q", node.Value)
+	}
+}
+
+type yamlField struct {
+	name string
+	typ  reflect.Type
+}
+
+// yamlFields поля структуры с их ключами YAML в порядке объявления
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{name: name, typ: field.Type})
+	}
+	return fields
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stdinPath путь, по которому флаги читаются из стандартного ввода
+const stdinPath = "-"
+
+// stdin источник флагов для пути "-", подменяется в тестах
+var stdin io.Reader = os.Stdin
+
+type format string
+
+const (
+	formatYAML format = "YAML"
+	formatJSON format = "JSON"
+)
+
+// formats форматы файлов флагов по расширению
+var formats = map[string]format{
+	".yaml": formatYAML,
+	".yml":  formatYAML,
+	".json": formatJSON,
+}
+
+func supportedFile(path string) bool {
+	_, ok := formats[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// sourceName имя файла в сообщениях об ошибках и в Position
+func sourceName(path string) string {
+	if path == stdinPath {
+		return "<stdin>"
+	}
+	return path
+}
+
+// expandPaths раскрывает каталоги и glob шаблоны в список файлов флагов.
+// Из каталогов, включая вложенные, берутся файлы .yaml, .yml и .json в лексическом порядке, повторы отбрасываются.
+func expandPaths(paths []string) ([]string, error) {
+	var fileNames []string
+	seen := make(map[string]bool)
+	add := func(fileName string) {
+		if clean := filepath.Clean(fileName); !seen[clean] {
+			seen[clean] = true
+			fileNames = append(fileNames, fileName)
+		}
+	}
+
+	for _, path := range paths {
+		if path == stdinPath {
+			add(path)
+			continue
+		}
+
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirFiles, err := flagsFilesInDir(path)
+			if err != nil {
+				return nil, err
+			}
+			if len(dirFiles) == 0 {
+				return nil, fmt.Errorf("no .yaml, .yml or .json files in directory %s", path)
+			}
+			for _, fileName := range dirFiles {
+				add(fileName)
+			}
+			continue
+		}
+
+		if !strings.ContainsAny(path, `*?[`) {
+			if !supportedFile(path) {
+				return nil, fmt.Errorf("flags file must have .yaml, .yml or .json extension")
+			}
+			add(path)
+			continue
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flags file pattern %q: %w", path, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no flags files match %q", path)
+		}
+		for _, match := range matches {
+			if !supportedFile(match) {
+				return nil, fmt.Errorf("flags file %s matched by %q must have .yaml, .yml or .json extension", match, path)
+			}
+			add(match)
+		}
+	}
+	return fileNames, nil
+}
+
+func flagsFilesInDir(dir string) ([]string, error) {
+	var fileNames []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && supportedFile(entry.Name()) {
+			fileNames = append(fileNames, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	return fileNames, nil
+}
+
+// readDocuments читает все документы файла. JSON разбирается тем же парсером YAML,
+// чтобы проверка полей и позиции ошибок не зависели от формата, но синтаксис
+// JSON файла предварительно проверяется, чтобы YAML в файле .json не прошёл незамеченным.
+func readDocuments(path string) ([]*yaml.Node, error) {
+	content, err := readContent(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := sourceName(path)
+	if detectFormat(path, content) == formatJSON {
+		var raw json.RawMessage
+		if err := json.Unmarshal(content, &raw); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// Offset указывает на байт после ошибочного символа
+				line, column := offsetPosition(content, syntaxErr.Offset-1)
+				return nil, fmt.Errorf("error unmarshalling JSON: %s:%d:%d: %w", name, line, column, err)
+			}
+			return nil, fmt.Errorf("error unmarshalling JSON: %s: %w", name, err)
+		}
+	}
+
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling YAML: %s: %w", name, err)
+		}
+		if len(document.Content) > 0 && document.Content[0].Tag != "!!null" {
+			documents = append(documents, document.Content[0])
+		}
+	}
+}
+
+func readContent(path string) ([]byte, error) {
+	if path == stdinPath {
+		content, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stdin: %w", err)
+		}
+		return content, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	return content, nil
+}
+
+// detectFormat определяет формат по расширению, а для стандартного ввода - по содержимому
+func detectFormat(path string, content []byte) format {
+	if path != stdinPath {
+		return formats[strings.ToLower(filepath.Ext(path))]
+	}
+	if json.Valid(content) {
+		return formatJSON
+	}
+	return formatYAML
+}
+
+// offsetPosition переводит смещение в байтах в строку и колонку, считая с 1
+func offsetPosition(content []byte, offset int64) (int, int) {
+	offset = max(0, min(offset, int64(len(content))))
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
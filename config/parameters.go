@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Параметры стратегий, значения которых являются списками через запятую и не зависят от порядка
+var listParameters = map[string]bool{
+	"userIds": true,
+}
+
+// Параметры стратегий с числовыми значениями: "50", 50 и "50.0" эквивалентны
+var numericParameters = map[string]bool{
+	"percentage": true,
+	"rollout":    true,
+}
+
+// NormalizeParameter приводит значение параметра к строке, как его хранит GitLab.
+// API GitLab принимает только строковые параметры, поэтому числа и списки из YAML
+// передаются в этой форме.
+func NormalizeParameter(key string, value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		s = ""
+	case string:
+		s = strings.TrimSpace(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, NormalizeParameter("", item))
+		}
+		s = strings.Join(items, ",")
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	switch {
+	case listParameters[key]:
+		items := strings.Split(s, ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		items = slices.DeleteFunc(items, func(item string) bool { return item == "" })
+		sort.Strings(items)
+		return strings.Join(slices.Compact(items), ",")
+	case numericParameters[key]:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return s
+}
+
+// NormalizeParameters приводит к строкам все параметры стратегии
+func NormalizeParameters(params map[string]interface{}) map[string]string {
+	normalized := make(map[string]string, len(params))
+	for key, value := range params {
+		normalized[key] = NormalizeParameter(key, value)
+	}
+	return normalized
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeParameter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		key      string
+		value    interface{}
+		expected string
+	}{
+		{name: "string", key: "groupId", value: "default", expected: "default"},
+		{name: "string with spaces", key: "groupId", value: " default ", expected: "default"},
+		{name: "yaml integer", key: "percentage", value: 50, expected: "50"},
+		{name: "json number", key: "percentage", value: float64(50), expected: "50"},
+		{name: "numeric string with fraction", key: "rollout", value: "50.0", expected: "50"},
+		{name: "non numeric value of numeric parameter", key: "rollout", value: "half", expected: "half"},
+		{name: "boolean", key: "enabled", value: true, expected: "true"},
+		{name: "nil", key: "groupId", value: nil, expected: ""},
+		{name: "user ids with spaces", key: "userIds", value: "1, 2, 3", expected: "1,2,3"},
+		{name: "user ids unordered", key: "userIds", value: "3,1,2", expected: "1,2,3"},
+		{name: "user ids duplicated and empty", key: "userIds", value: "2,,1,2,", expected: "1,2"},
+		{name: "user ids as yaml list", key: "userIds", value: []interface{}{"b", 2, "a"}, expected: "2,a,b"},
+		{name: "other list keeps order", key: "hosts", value: "b,a", expected: "b,a"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, NormalizeParameter(tc.key, tc.value))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import "fmt"
+
+// allEnvironments окружение GitLab, которое подходит под любое другое
+const allEnvironments = "*"
+
+// IsActive сообщает, включено ли окружение. Без явного active окружение включено.
+func (s Scope) IsActive() bool {
+	return s.Active == nil || *s.Active
+}
+
+// CompileScopes переводит выключенные окружения в то, что умеет GitLab, где у окружения нет признака active:
+// выключенное окружение убирается из стратегии, стратегия без окружений удаляется,
+// а флаг, у которого не осталось стратегий, выключается целиком.
+// Предупреждения описывают выключенные окружения, которые всё равно останутся включены другими стратегиями.
+func CompileScopes(flag FeatureFlag) (FeatureFlag, []string) {
+	compiled := flag
+	if flag.Strategies != nil {
+		compiled.Strategies = make([]Strategy, 0, len(flag.Strategies))
+	}
+
+	type inactiveScope struct {
+		strategy    string
+		environment string
+	}
+	var inactive []inactiveScope
+	for _, strategy := range flag.Strategies {
+		scopes := make([]Scope, 0, len(strategy.Scopes))
+		disabled := 0
+		for _, scope := range strategy.Scopes {
+			if !scope.IsActive() {
+				disabled++
+				inactive = append(inactive, inactiveScope{strategy: strategy.Name, environment: scope.Environment})
+				continue
+			}
+			scopes = append(scopes, Scope{Environment: scope.Environment})
+		}
+		if disabled > 0 && len(scopes) == 0 {
+			continue
+		}
+		strategy.Scopes = scopes
+		compiled.Strategies = append(compiled.Strategies, strategy)
+	}
+
+	var warnings []string
+	for _, scope := range inactive {
+		for _, strategy := range compiled.Strategies {
+			if covered, ok := coveringScope(strategy, scope.environment); ok {
+				warnings = append(warnings, fmt.Sprintf(
+					"flag %s: environment %q is inactive in strategy %s but stays enabled by strategy %s with scope %q, GitLab cannot exclude an environment",
+					flag.Name, scope.environment, scope.strategy, strategy.Name, covered))
+				break
+			}
+		}
+	}
+
+	if len(inactive) > 0 && len(flag.Strategies) > 0 && len(compiled.Strategies) == 0 && flag.Active {
+		compiled.Active = false
+		warnings = append(warnings, fmt.Sprintf("flag %s: all scopes are inactive, the flag will be deactivated", flag.Name))
+	}
+	return compiled, warnings
+}
+
+// coveringScope ищет в стратегии окружение, под которое подходит environment
+func coveringScope(strategy Strategy, environment string) (string, bool) {
+	for _, scope := range strategy.Scopes {
+		if scope.Environment == environment || scope.Environment == allEnvironments {
+			return scope.Environment, true
+		}
+	}
+	return "", false
+}
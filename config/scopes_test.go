@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileScopes(t *testing.T) {
+	on, off := true, false
+	scope := func(env string, active *bool) Scope {
+		return Scope{Environment: env, Active: active}
+	}
+
+	testCases := []struct {
+		name     string
+		flag     FeatureFlag
+		expected FeatureFlag
+		warnings []string
+	}{
+		{
+			name: "Scopes without active are kept",
+			flag: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "default", Scopes: []Scope{scope("PROD", nil), scope("TEST", &on)}},
+			}},
+			expected: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "default", Scopes: []Scope{scope("PROD", nil), scope("TEST", nil)}},
+			}},
+		},
+		{
+			name: "Inactive scope is dropped",
+			flag: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "default", Scopes: []Scope{scope("PROD", &off), scope("TEST", &on)}},
+			}},
+			expected: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "default", Scopes: []Scope{scope("TEST", nil)}},
+			}},
+		},
+		{
+			name: "Strategy without active scopes is dropped",
+			flag: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "userWithId", Scopes: []Scope{scope("PROD", &off)}},
+				{Name: "default", Scopes: []Scope{scope("TEST", nil)}},
+			}},
+			expected: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "default", Scopes: []Scope{scope("TEST", nil)}},
+			}},
+		},
+		{
+			name: "Flag without active scopes is deactivated",
+			flag: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "default", Scopes: []Scope{scope("PROD", &off), scope("TEST", &off)}},
+			}},
+			expected: FeatureFlag{Name: "f", Active: false, Strategies: []Strategy{}},
+			warnings: []string{"flag f: all scopes are inactive, the flag will be deactivated"},
+		},
+		{
+			name: "Inactive scope covered by another strategy",
+			flag: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "userWithId", Scopes: []Scope{scope("PROD", &off), scope("TEST", nil)}},
+				{Name: "default", Scopes: []Scope{scope("*", nil)}},
+			}},
+			expected: FeatureFlag{Name: "f", Active: true, Strategies: []Strategy{
+				{Name: "userWithId", Scopes: []Scope{scope("TEST", nil)}},
+				{Name: "default", Scopes: []Scope{scope("*", nil)}},
+			}},
+			warnings: []string{`flag f: environment "PROD" is inactive in strategy userWithId but stays enabled by strategy default with scope "*", GitLab cannot exclude an environment`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compiled, warnings := CompileScopes(tc.flag)
+			assert.Equal(t, tc.expected, compiled)
+			assert.Equal(t, tc.warnings, warnings)
+		})
+	}
+}
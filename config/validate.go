@@ -0,0 +1,253 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Стратегии, которые поддерживает GitLab
+const (
+	StrategyDefault              = "default"
+	StrategyGradualRolloutUserID = "gradualRolloutUserId"
+	StrategyUserWithID           = "userWithId"
+	StrategyGitLabUserList       = "gitlabUserList"
+	StrategyFlexibleRollout      = "flexibleRollout"
+)
+
+// strategyParameters параметры каждой стратегии, все они обязательны
+var strategyParameters = map[string][]string{
+	StrategyDefault:              {},
+	StrategyGradualRolloutUserID: {"groupId", "percentage"},
+	StrategyUserWithID:           {"userIds"},
+	StrategyGitLabUserList:       {},
+	StrategyFlexibleRollout:      {"groupId", "rollout", "stickiness"},
+}
+
+// Значения stickiness стратегии flexibleRollout
+var stickinessValues = []string{"default", "userId", "sessionId", "random"}
+
+// flagNamePattern имена флагов, которые принимает GitLab
+var flagNamePattern = regexp.MustCompile(`^[a-z]([-_a-z0-9]*[a-z0-9])?$`)
+
+// groupIDPattern значения groupId, которые принимает GitLab
+var groupIDPattern = regexp.MustCompile(`^[a-z]{1,32}$`)
+
+// maxDescriptionLength максимальная длина описания флага в GitLab в символах
+const maxDescriptionLength = 255
+
+// ValidationError нарушение правил GitLab в конфигурации флагов
+type ValidationError struct {
+	Source Position
+	// Owner флаг или список пользователей, к которому относится ошибка
+	Owner   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if source := e.Source.String(); source != "" {
+		return fmt.Sprintf("%s: %s: %s", source, e.Owner, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Owner, e.Message)
+}
+
+// ValidationErrors все нарушения, найденные в конфигурации
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%d validation errors:", len(e)))
+	for _, err := range e {
+		lines = append(lines, "\t"+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate проверяет конфигурацию по правилам GitLab без обращения к API:
+// имена флагов и их уникальность, имена стратегий, обязательные параметры и их значения,
+// ссылки на списки пользователей. Возвращает ValidationErrors со всеми нарушениями или nil.
+func Validate(cfg *Config) error {
+	v := validator{}
+
+	lists := make(map[string]UserList, len(cfg.UserLists))
+	for _, list := range cfg.UserLists {
+		owner := "user list " + list.Name
+		if first, exists := lists[list.Name]; exists {
+			v.report(list.Source, owner, "defined more than once, first %s", describeSource(first.Source))
+			continue
+		}
+		lists[list.Name] = list
+		if strings.TrimSpace(list.Name) == "" {
+			v.report(list.Source, "user list", "name is empty")
+		}
+		if len(list.UserXIDs) == 0 {
+			v.report(list.Source, owner, "user_xids is empty")
+		}
+	}
+
+	flags := make(map[string]FeatureFlag, len(cfg.Flags))
+	for _, flag := range cfg.Flags {
+		if first, exists := flags[flag.Name]; exists {
+			v.report(flag.Source, "flag "+flag.Name, "defined more than once, first %s", describeSource(first.Source))
+			continue
+		}
+		flags[flag.Name] = flag
+		v.flag(flag, lists, cfg.UserLists != nil)
+	}
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+type validator struct {
+	errors ValidationErrors
+}
+
+func (v *validator) report(source Position, owner, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Source: source, Owner: owner, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) flag(flag FeatureFlag, lists map[string]UserList, listsManaged bool) {
+	owner := "flag " + flag.Name
+	if flag.Name == "" {
+		v.report(flag.Source, "flag", "name is empty")
+	} else if !flagNamePattern.MatchString(flag.Name) {
+		v.report(flag.Source, owner, "name must contain only lowercase letters, digits, '_' and '-', start with a letter and not end with '_' or '-'")
+	}
+	if length := managedDescriptionLength(flag.Description); length > maxDescriptionLength {
+		v.report(flag.Source, owner, "description is %d characters long together with the %q suffix, GitLab allows %d",
+			length, ManagedMarker, maxDescriptionLength)
+	}
+
+	for i, strategy := range flag.Strategies {
+		prefix := fmt.Sprintf("strategies[%d] %s", i, strategy.Name)
+		required, known := strategyParameters[strategy.Name]
+		if !known {
+			v.report(flag.Source, owner, "strategies[%d]: unknown strategy %q (expected one of: %s)", i, strategy.Name, strings.Join(strategyNames(), ", "))
+			continue
+		}
+
+		for _, name := range required {
+			if _, ok := strategy.Parameters[name]; !ok {
+				v.report(flag.Source, owner, "%s: parameter %s is required", prefix, name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(strategy.Parameters)) {
+			if !slices.Contains(required, name) {
+				v.report(flag.Source, owner, "%s: unknown parameter %s", prefix, name)
+				continue
+			}
+			if msg := checkParameter(name, strategy.Parameters[name]); msg != "" {
+				v.report(flag.Source, owner, "%s: parameter %s %s", prefix, name, msg)
+			}
+		}
+
+		switch {
+		case strategy.Name == StrategyGitLabUserList && strategy.UserList == "":
+			v.report(flag.Source, owner, "%s: user_list is required", prefix)
+		case strategy.Name != StrategyGitLabUserList && strategy.UserList != "":
+			v.report(flag.Source, owner, "%s: user_list is only allowed for %s", prefix, StrategyGitLabUserList)
+		case strategy.UserList != "" && listsManaged:
+			if _, exists := lists[strategy.UserList]; !exists {
+				v.report(flag.Source, owner, "%s: user list %q is not defined in userLists", prefix, strategy.UserList)
+			}
+		}
+
+		for _, scope := range strategy.Scopes {
+			if strings.TrimSpace(scope.Environment) == "" {
+				v.report(flag.Source, owner, "%s: environment_scope is empty", prefix)
+			}
+		}
+	}
+}
+
+// checkParameter проверяет значение параметра и возвращает описание нарушения или пустую строку.
+// Числа допускаются и передаются в GitLab строкой, как и список userIds.
+func checkParameter(name string, value interface{}) string {
+	if msg := checkParameterType(name, value); msg != "" {
+		return msg
+	}
+	s := NormalizeParameter(name, value)
+	if s == "" {
+		return "is empty"
+	}
+	switch name {
+	case "percentage", "rollout":
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 100 {
+			return fmt.Sprintf("must be an integer from 0 to 100, got %q", s)
+		}
+	case "stickiness":
+		if !slices.Contains(stickinessValues, s) {
+			return fmt.Sprintf("must be one of %s, got %q", strings.Join(stickinessValues, ", "), s)
+		}
+	case "groupId":
+		if !groupIDPattern.MatchString(s) {
+			return fmt.Sprintf("must contain only 1 to 32 lowercase letters, got %q", s)
+		}
+	case "userIds":
+		return checkUserIDs(s)
+	}
+	return ""
+}
+
+// checkParameterType допускает строки и числа, а для userIds ещё и список из них
+func checkParameterType(name string, value interface{}) string {
+	switch v := value.(type) {
+	case nil, string, int, int64, uint64, float64:
+		return ""
+	case []interface{}:
+		if name != "userIds" {
+			return "must be a string, got a list"
+		}
+		for _, item := range v {
+			if msg := checkParameterType("", item); msg != "" {
+				return "items " + msg
+			}
+		}
+		return ""
+	case map[string]interface{}:
+		return "must be a string, got a mapping"
+	default:
+		return fmt.Sprintf("must be a string, got %v", v)
+	}
+}
+
+// checkUserIDs проверяет userIds в том виде, в каком они уйдут в GitLab после NormalizeParameter,
+// поэтому пробелы и повторы не считаются ошибкой
+func checkUserIDs(normalized string) string {
+	if slices.Contains(strings.Split(normalized, ","), "") {
+		return fmt.Sprintf("must be a comma separated list without empty items, got %q", normalized)
+	}
+	return ""
+}
+
+// managedDescriptionLength длина описания в символах после того, как к нему добавится ManagedMarker
+func managedDescriptionLength(description string) int {
+	length := utf8.RuneCountInString(description)
+	switch {
+	case strings.HasSuffix(strings.TrimSpace(description), ManagedMarker):
+		return length
+	case description == "":
+		return utf8.RuneCountInString(ManagedMarker)
+	default:
+		return length + utf8.RuneCountInString(" "+ManagedMarker)
+	}
+}
+
+func describeSource(source Position) string {
+	if s := source.String(); s != "" {
+		return "at " + s
+	}
+	return "earlier"
+}
+
+func strategyNames() []string {
+	return slices.Sorted(maps.Keys(strategyParameters))
+}
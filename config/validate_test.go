@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	scopes := []Scope{{Environment: "*"}}
+
+	t.Run("valid configuration", func(t *testing.T) {
+		cfg := &Config{
+			UserLists: []UserList{{Name: "beta", UserXIDs: []string{"alice"}}},
+			Flags: []FeatureFlag{
+				{Name: "new-ui", Strategies: []Strategy{{Name: StrategyDefault, Parameters: map[string]interface{}{}, Scopes: scopes}}},
+				{Name: "checkout_v2", Strategies: []Strategy{
+					{Name: StrategyGradualRolloutUserID, Parameters: map[string]interface{}{"percentage": 50, "groupId": "default"}, Scopes: scopes},
+					{Name: StrategyUserWithID, Parameters: map[string]interface{}{"userIds": "1,2,3"}, Scopes: scopes},
+					{Name: StrategyUserWithID, Parameters: map[string]interface{}{"userIds": []interface{}{1, "alice"}}, Scopes: scopes},
+					{Name: StrategyUserWithID, Parameters: map[string]interface{}{"userIds": "1, 2, 3, 1"}, Scopes: scopes},
+					{Name: StrategyFlexibleRollout, Parameters: map[string]interface{}{"rollout": "100", "stickiness": "userId", "groupId": "default"}, Scopes: scopes},
+					{Name: StrategyGitLabUserList, UserList: "beta", Scopes: scopes},
+				}},
+			},
+		}
+
+		assert.NoError(t, Validate(cfg))
+	})
+
+	testCases := []struct {
+		name     string
+		flag     FeatureFlag
+		expected string
+	}{
+		{
+			name:     "Invalid name",
+			flag:     FeatureFlag{Name: "New_UI"},
+			expected: "flag New_UI: name must contain only lowercase letters",
+		},
+		{
+			name:     "Name ending with separator",
+			flag:     FeatureFlag{Name: "new_ui_"},
+			expected: "flag new_ui_: name must contain only lowercase letters",
+		},
+		{
+			name:     "Description too long",
+			flag:     FeatureFlag{Name: "f", Description: strings.Repeat("a", 228)},
+			expected: `flag f: description is 256 characters long together with the "[managed by gitlab-flagman]" suffix, GitLab allows 255`,
+		},
+		{
+			name:     "Unknown strategy",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: "usersWithId", Scopes: scopes}}},
+			expected: `flag f: strategies[0]: unknown strategy "usersWithId"`,
+		},
+		{
+			name:     "Missing parameter",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyGradualRolloutUserID, Parameters: map[string]interface{}{"percentage": 10}, Scopes: scopes}}},
+			expected: "flag f: strategies[0] gradualRolloutUserId: parameter groupId is required",
+		},
+		{
+			name:     "Unknown parameter",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyDefault, Parameters: map[string]interface{}{"percentage": 10}, Scopes: scopes}}},
+			expected: "flag f: strategies[0] default: unknown parameter percentage",
+		},
+		{
+			name:     "Percentage out of range",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyGradualRolloutUserID, Parameters: map[string]interface{}{"percentage": 150, "groupId": "default"}, Scopes: scopes}}},
+			expected: `flag f: strategies[0] gradualRolloutUserId: parameter percentage must be an integer from 0 to 100, got "150"`,
+		},
+		{
+			name:     "Unknown stickiness",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyFlexibleRollout, Parameters: map[string]interface{}{"rollout": 10, "stickiness": "sticky", "groupId": "default"}, Scopes: scopes}}},
+			expected: `flag f: strategies[0] flexibleRollout: parameter stickiness must be one of default, userId, sessionId, random, got "sticky"`,
+		},
+		{
+			name:     "Empty user IDs",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyUserWithID, Parameters: map[string]interface{}{"userIds": " , "}, Scopes: scopes}}},
+			expected: `flag f: strategies[0] userWithId: parameter userIds is empty`,
+		},
+		{
+			name:     "Invalid group ID",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyGradualRolloutUserID, Parameters: map[string]interface{}{"percentage": "10", "groupId": "new-ui"}, Scopes: scopes}}},
+			expected: `flag f: strategies[0] gradualRolloutUserId: parameter groupId must contain only 1 to 32 lowercase letters, got "new-ui"`,
+		},
+		{
+			name:     "List value",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyGradualRolloutUserID, Parameters: map[string]interface{}{"percentage": []interface{}{10}, "groupId": "default"}, Scopes: scopes}}},
+			expected: "flag f: strategies[0] gradualRolloutUserId: parameter percentage must be a string, got a list",
+		},
+		{
+			name:     "Boolean value",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyFlexibleRollout, Parameters: map[string]interface{}{"rollout": "10", "stickiness": true, "groupId": "default"}, Scopes: scopes}}},
+			expected: "flag f: strategies[0] flexibleRollout: parameter stickiness must be a string, got true",
+		},
+		{
+			name:     "User list without gitlabUserList",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyDefault, UserList: "beta", Scopes: scopes}}},
+			expected: "flag f: strategies[0] default: user_list is only allowed for gitlabUserList",
+		},
+		{
+			name:     "Unknown user list",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyGitLabUserList, UserList: "alpha", Scopes: scopes}}},
+			expected: `flag f: strategies[0] gitlabUserList: user list "alpha" is not defined in userLists`,
+		},
+		{
+			name:     "Empty environment scope",
+			flag:     FeatureFlag{Name: "f", Strategies: []Strategy{{Name: StrategyDefault, Scopes: []Scope{{Environment: " "}}}}},
+			expected: "flag f: strategies[0] default: environment_scope is empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(&Config{Flags: []FeatureFlag{tc.flag}, UserLists: []UserList{}})
+
+			var validationErrs ValidationErrors
+			require.True(t, errors.As(err, &validationErrs))
+			require.Len(t, validationErrs, 1)
+			assert.Contains(t, validationErrs[0].Error(), tc.expected)
+		})
+	}
+
+	t.Run("description length is counted in characters", func(t *testing.T) {
+		cfg := &Config{Flags: []FeatureFlag{
+			{Name: "cyrillic", Description: strings.Repeat("ж", 227)},
+			{Name: "marked", Description: strings.Repeat("a", 227) + " " + ManagedMarker},
+		}}
+
+		assert.NoError(t, Validate(cfg))
+	})
+
+	t.Run("duplicates are reported with both positions", func(t *testing.T) {
+		cfg := &Config{
+			UserLists: []UserList{
+				{Name: "beta", UserXIDs: []string{"a"}, Source: Position{File: "flags.yaml", Line: 2, Column: 5}},
+				{Name: "beta", UserXIDs: []string{"b"}, Source: Position{File: "flags.yaml", Line: 4, Column: 5}},
+			},
+			Flags: []FeatureFlag{
+				{Name: "f", Source: Position{File: "flags.yaml", Line: 7, Column: 5}},
+				{Name: "f", Source: Position{File: "flags.yaml", Line: 9, Column: 5}},
+			},
+		}
+
+		err := Validate(cfg)
+
+		assert.EqualError(t, err, `2 validation errors:
+	flags.yaml:4:5: user list beta: defined more than once, first at flags.yaml:2:5
+	flags.yaml:9:5: flag f: defined more than once, first at flags.yaml:7:5`)
+	})
+}
@@ -4,29 +4,135 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nkrus/gitlab-flagman/internal/client"
+	"github.com/nkrus/gitlab-flagman/internal/service"
 )
 
 type Args struct {
-	FlagsFile            string
+	Command string
+	// FlagsFiles файлы, каталоги или glob шаблоны с флагами
+	FlagsFiles           []string
 	GitLabBase           string
 	GitLabToken          string
+	GitLabTokenFile      string
+	GitLabTokenEnv       string
+	GitLabAuth           string
+	GitLabCACert         string
+	GitLabClientCert     string
+	GitLabClientKey      string
+	GitLabProxy          string
+	GitLabInsecure       bool
+	StateFile            string
 	GitLabProjectID      string
 	GitLabRequestTimeout int
+	GitLabRetries        int
+	GitLabRetryDelay     time.Duration
+	GitLabRetryMaxDelay  time.Duration
+	GitLabRateLimit      float64
+	GitLabRateBurst      int
+	GitLabPageSize       int
+	DryRun               bool
+	Prune                bool
+	PruneUserLists       bool
+	MaxDeletes           string
+	Force                bool
+	ContinueOnError      bool
+	RollbackOnFailure    bool
+	PlanOut              string
+	DriftReport          string
+	Only                 []string
+	Exclude              []string
+	Tags                 []string
+	// FlagNames имена флагов, переданные после команды adopt
+	FlagNames []string
+	// PlanFile путь к плану, переданный после команды apply
+	PlanFile string
+	// DeletionLimit разобранное значение -max-deletes, nil если ограничение не задано
+	DeletionLimit *service.DeletionLimit
 }
 
 var args Args
 
 const (
-	defaultFlagsFile  = "feature_flags.yaml"
-	defaultGitLabBase = "https://gitlab.com/api/v4"
+	defaultFlagsFile           = "feature_flags.yaml"
+	defaultGitLabBase          = "https://gitlab.com/api/v4"
+	defaultGitLabRetries       = 3
+	defaultGitLabRetryDelay    = time.Second
+	defaultGitLabRetryMaxDelay = 30 * time.Second
+	defaultGitLabRateLimit     = 10
+	defaultGitLabRateBurst     = 5
+	defaultGitLabPageSize      = 100
+	defaultGitLabAuth          = client.AuthPrivateToken
+	ciJobTokenEnv              = "CI_JOB_TOKEN"
+)
+
+// Команды, передаваемые первым позиционным аргументом
+const (
+	CommandSync     = "sync"     // синхронизировать флаги (по умолчанию)
+	CommandPlan     = "plan"     // показать план изменений без применения
+	CommandAdopt    = "adopt"    // взять под управление флаги, созданные вручную
+	CommandApply    = "apply"    // применить план, сохранённый командой plan -out
+	CommandDrift    = "drift"    // найти отличия GitLab от конфигурации, ничего не изменяя
+	CommandValidate = "validate" // проверить файл флагов без обращения к GitLab
 )
 
 func RegisterFlags() {
-	flag.StringVar(&args.FlagsFile, "flagsFile", defaultFlagsFile, "Путь к файлу с фичами")
+	flag.Var((*stringList)(&args.FlagsFiles), "flagsFile", "Файл с фичами, каталог или glob шаблон (можно повторять), по умолчанию "+defaultFlagsFile)
 	flag.StringVar(&args.GitLabBase, "gitLabBase", defaultGitLabBase, "Базовый URL GitLab API")
 	flag.StringVar(&args.GitLabToken, "gitLabToken", "", "Токен доступа к GitLab")
-	flag.StringVar(&args.GitLabProjectID, "gitLabProjectID", "", "ID проекта в GitLab")
+	flag.StringVar(&args.GitLabTokenFile, "gitLabTokenFile", "", "Прочитать токен доступа к GitLab из файла")
+	flag.StringVar(&args.GitLabTokenEnv, "gitLabTokenEnv", "", "Прочитать токен доступа к GitLab из переменной окружения")
+	flag.StringVar(&args.GitLabAuth, "gitLabAuth", defaultGitLabAuth, "Способ аутентификации: private, project, job (CI_JOB_TOKEN) или oauth")
+	flag.StringVar(&args.GitLabProjectID, "gitLabProjectID", "", "ID проекта в GitLab или его путь, например group/subgroup/project")
+	flag.StringVar(&args.GitLabCACert, "gitLabCACert", "", "PEM файл с сертификатами CA, которым доверять в дополнение к системным")
+	flag.StringVar(&args.GitLabClientCert, "gitLabClientCert", "", "Клиентский сертификат для mutual TLS")
+	flag.StringVar(&args.GitLabClientKey, "gitLabClientKey", "", "Ключ клиентского сертификата для mutual TLS")
+	flag.StringVar(&args.GitLabProxy, "gitLabProxy", "", "Адрес HTTP(S) прокси, по умолчанию берётся из HTTPS_PROXY/HTTP_PROXY")
+	flag.BoolVar(&args.GitLabInsecure, "gitLabInsecureSkipVerify", false, "Не проверять сертификат GitLab (небезопасно, только для тестовых стендов)")
 	flag.IntVar(&args.GitLabRequestTimeout, "gitLabRequestTimeout", 10, "Таймаут ожидания ответа от Gitlab")
+	flag.IntVar(&args.GitLabRetries, "gitLabRetries", defaultGitLabRetries, "Число повторов запроса к GitLab при сетевых ошибках, 429 и 5xx")
+	flag.DurationVar(&args.GitLabRetryDelay, "gitLabRetryDelay", defaultGitLabRetryDelay, "Задержка перед первым повтором, далее удваивается")
+	flag.DurationVar(&args.GitLabRetryMaxDelay, "gitLabRetryMaxDelay", defaultGitLabRetryMaxDelay, "Максимальная задержка между повторами")
+	flag.Float64Var(&args.GitLabRateLimit, "gitLabRateLimit", defaultGitLabRateLimit, "Максимум запросов к GitLab в секунду, 0 - без ограничения")
+	flag.IntVar(&args.GitLabRateBurst, "gitLabRateBurst", defaultGitLabRateBurst, "Число запросов, которые можно отправить сразу, не дожидаясь лимита")
+	flag.IntVar(&args.GitLabPageSize, "gitLabPageSize", defaultGitLabPageSize, "Число флагов на странице при получении списка из GitLab, от 1 до 100")
+	flag.StringVar(&args.StateFile, "stateFile", "", "Синхронизировать с локальным JSON файлом вместо GitLab, для репетиции без доступа к GitLab")
+	flag.BoolVar(&args.DryRun, "dry-run", false, "Показать план изменений, не изменяя флаги в GitLab")
+	flag.BoolVar(&args.Prune, "prune", false, "Удалять из GitLab флаги, отсутствующие в файле")
+	flag.BoolVar(&args.PruneUserLists, "prune-user-lists", false, "Удалять из GitLab неиспользуемые списки пользователей, отсутствующие в разделе userLists")
+	flag.StringVar(&args.MaxDeletes, "max-deletes", "", "Максимум удалений за запуск: число или процент от флагов в GitLab (например 10 или 25%)")
+	flag.BoolVar(&args.Force, "force", false, "Удалять флаги сверх -max-deletes")
+	flag.BoolVar(&args.ContinueOnError, "continue-on-error", false, "Продолжать синхронизацию остальных флагов после ошибки")
+	flag.BoolVar(&args.RollbackOnFailure, "rollback-on-failure", false, "При ошибке вернуть затронутые флаги к состоянию до синхронизации")
+	flag.StringVar(&args.PlanOut, "out", "", "Сохранить план в файл для команды apply")
+	flag.StringVar(&args.DriftReport, "report", "", "Сохранить JSON отчёт команды drift в файл")
+	flag.Var((*stringList)(&args.Only), "only", "Синхронизировать только флаги, имена которых соответствуют glob шаблону (можно повторять или перечислять через запятую)")
+	flag.Var((*stringList)(&args.Exclude), "exclude", "Не синхронизировать флаги, имена которых соответствуют glob шаблону (можно повторять или перечислять через запятую)")
+	flag.Var((*stringList)(&args.Tags), "tags", "Синхронизировать только флаги с одним из тегов (можно повторять или перечислять через запятую)")
+}
+
+// stringList значение флага, которое можно повторять или перечислять через запятую
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
 }
 func init() {
 	RegisterFlags()
@@ -35,11 +141,59 @@ func init() {
 func ParseArgs() (*Args, error) {
 	flag.Parse()
 
-	if !isFlagPassed("gitLabToken") {
-		return nil, fmt.Errorf("-gitLabToken обязателен")
+	if err := parseCommand(); err != nil {
+		return nil, err
+	}
+	if len(args.FlagsFiles) == 0 {
+		args.FlagsFiles = []string{defaultFlagsFile}
+	}
+	if args.Command == CommandApply {
+		// План уже построен, настройки выбора флагов и удаления применить к нему нельзя
+		for _, name := range []string{"prune", "prune-user-lists", "only", "exclude", "tags", "out"} {
+			if isFlagPassed(name) {
+				return nil, fmt.Errorf("-%s нельзя использовать с командой apply, он учитывается при построении плана", name)
+			}
+		}
 	}
-	if !isFlagPassed("gitLabProjectID") {
-		return nil, fmt.Errorf("-gitLabProjectID обязателен")
+	if args.PlanOut != "" && !args.DryRun {
+		return nil, fmt.Errorf("-out используется только с командой plan или -dry-run")
+	}
+	if args.MaxDeletes != "" {
+		limit, err := service.ParseDeletionLimit(args.MaxDeletes)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный -max-deletes: %w", err)
+		}
+		args.DeletionLimit = &limit
+	}
+	if args.GitLabRetries < 0 {
+		return nil, fmt.Errorf("-gitLabRetries не может быть отрицательным")
+	}
+	if args.GitLabRateLimit < 0 || args.GitLabRateBurst < 1 {
+		return nil, fmt.Errorf("-gitLabRateLimit не может быть отрицательным, -gitLabRateBurst должен быть не меньше 1")
+	}
+	if (args.GitLabClientCert == "") != (args.GitLabClientKey == "") {
+		return nil, fmt.Errorf("-gitLabClientCert и -gitLabClientKey указываются вместе")
+	}
+	if args.GitLabPageSize < 1 || args.GitLabPageSize > 100 {
+		return nil, fmt.Errorf("-gitLabPageSize должен быть от 1 до 100")
+	}
+	if args.DriftReport != "" && args.Command != CommandDrift {
+		return nil, fmt.Errorf("-report используется только с командой drift")
+	}
+	for _, pattern := range append(append([]string{}, args.Only...), args.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("некорректный шаблон %q: %w", pattern, err)
+		}
+	}
+
+	// С -stateFile и командой validate GitLab не используется, поэтому токен и проект не нужны
+	if args.StateFile == "" && args.Command != CommandValidate {
+		if err := resolveToken(); err != nil {
+			return nil, err
+		}
+		if !isFlagPassed("gitLabProjectID") {
+			return nil, fmt.Errorf("-gitLabProjectID обязателен")
+		}
 	}
 
 	logArgs(&args)
@@ -47,6 +201,103 @@ func ParseArgs() (*Args, error) {
 	return &args, nil
 }
 
+// resolveToken читает токен из -gitLabToken, -gitLabTokenFile или -gitLabTokenEnv.
+// Для -gitLabAuth job без явного источника используется CI_JOB_TOKEN.
+func resolveToken() error {
+	switch args.GitLabAuth {
+	case client.AuthPrivateToken, client.AuthProjectToken, client.AuthJobToken, client.AuthOAuth:
+	default:
+		return fmt.Errorf("неизвестный способ аутентификации %q", args.GitLabAuth)
+	}
+
+	sources := 0
+	for _, name := range []string{"gitLabToken", "gitLabTokenFile", "gitLabTokenEnv"} {
+		if isFlagPassed(name) {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("укажите только один из -gitLabToken, -gitLabTokenFile, -gitLabTokenEnv")
+	}
+
+	switch {
+	case isFlagPassed("gitLabTokenFile"):
+		data, err := os.ReadFile(args.GitLabTokenFile)
+		if err != nil {
+			return fmt.Errorf("не удалось прочитать -gitLabTokenFile: %w", err)
+		}
+		args.GitLabToken = strings.TrimSpace(string(data))
+	case isFlagPassed("gitLabTokenEnv"):
+		args.GitLabToken = strings.TrimSpace(os.Getenv(args.GitLabTokenEnv))
+	case sources == 0 && args.GitLabAuth == client.AuthJobToken:
+		args.GitLabToken = os.Getenv(ciJobTokenEnv)
+	}
+
+	if args.GitLabToken == "" {
+		return fmt.Errorf("-gitLabToken обязателен (или -gitLabTokenFile, -gitLabTokenEnv)")
+	}
+	return nil
+}
+
+// parseCommand читает команду из первого позиционного аргумента.
+// Флаги можно указывать до команды, после неё и между её аргументами.
+func parseCommand() error {
+	args.Command = CommandSync
+	if flag.NArg() == 0 {
+		return nil
+	}
+
+	command := flag.Arg(0)
+	switch command {
+	case CommandSync, CommandPlan, CommandAdopt, CommandApply, CommandDrift, CommandValidate:
+	default:
+		return fmt.Errorf("неизвестная команда %q", command)
+	}
+	args.Command = command
+
+	positional, err := parseInterspersed(flag.Args()[1:])
+	if err != nil {
+		return err
+	}
+	switch {
+	case command == CommandAdopt:
+		args.FlagNames = positional
+	case command == CommandApply:
+		if len(positional) != 1 {
+			return fmt.Errorf("команде apply нужен ровно один путь к файлу плана")
+		}
+		args.PlanFile = positional[0]
+	case len(positional) > 0:
+		return fmt.Errorf("неожиданные аргументы: %v", positional)
+	}
+
+	if command == CommandPlan {
+		args.DryRun = true
+	}
+	return nil
+}
+
+// parseInterspersed разбирает флаги, перемежающиеся с позиционными аргументами, и возвращает
+// позиционные. После "--" все оставшиеся аргументы считаются позиционными.
+func parseInterspersed(arguments []string) ([]string, error) {
+	var positional []string
+	for len(arguments) > 0 {
+		if err := flag.CommandLine.Parse(arguments); err != nil {
+			return nil, err
+		}
+		rest := flag.Args()
+		if consumed := len(arguments) - len(rest); consumed > 0 && arguments[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		arguments = rest[1:]
+	}
+	return positional, nil
+}
+
 func isFlagPassed(name string) bool {
 	found := false
 	flag.Visit(func(f *flag.Flag) {
@@ -61,10 +312,36 @@ func logArgs(config *Args) {
 	log.Printf(`
 Using parameters:
 -------------------- 
+command: %s 
+dryRun: %t 
+prune: %t 
+pruneUserLists: %t 
+maxDeletes: %q 
+force: %t 
+continueOnError: %t 
+rollbackOnFailure: %t 
+only: %q 
+exclude: %q 
+tags: %q 
 flagsFile: %q 
+stateFile: %q 
 gitLabBase: %q 
 gitLabProjectID: %q 
+gitLabAuth: %s 
+gitLabCACert: %q 
+gitLabClientCert: %q 
+gitLabProxy: %q 
+gitLabInsecureSkipVerify: %t 
 gitLabRequestTimeout: %ds
+gitLabRetries: %d (delay %s, max %s)
+gitLabRateLimit: %g/s (burst %d)
+gitLabPageSize: %d
 -------------------- `,
-		config.FlagsFile, config.GitLabBase, config.GitLabProjectID, config.GitLabRequestTimeout)
+		config.Command, config.DryRun, config.Prune, config.PruneUserLists, config.MaxDeletes, config.Force,
+		config.ContinueOnError, config.RollbackOnFailure, config.Only, config.Exclude, config.Tags,
+		config.FlagsFiles, config.StateFile, config.GitLabBase, config.GitLabProjectID, config.GitLabAuth,
+		config.GitLabCACert, config.GitLabClientCert, config.GitLabProxy, config.GitLabInsecure, config.GitLabRequestTimeout,
+		config.GitLabRetries, config.GitLabRetryDelay, config.GitLabRetryMaxDelay,
+		config.GitLabRateLimit, config.GitLabRateBurst,
+		config.GitLabPageSize)
 }
@@ -2,8 +2,11 @@ package args
 
 import (
 	"flag"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/nkrus/gitlab-flagman/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -12,6 +15,7 @@ func TestParseArgs(t *testing.T) {
 	testCases := []struct {
 		name          string
 		flags         map[string]string
+		args          []string
 		expectedError string
 		expectedArgs  Args
 	}{
@@ -23,11 +27,19 @@ func TestParseArgs(t *testing.T) {
 			},
 			expectedError: "",
 			expectedArgs: Args{
-				FlagsFile:            defaultFlagsFile,
+				Command:              CommandSync,
+				FlagsFiles:           []string{defaultFlagsFile},
 				GitLabBase:           defaultGitLabBase,
 				GitLabToken:          "token123",
 				GitLabProjectID:      "123456",
 				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
 			},
 		},
 		{
@@ -44,11 +56,369 @@ func TestParseArgs(t *testing.T) {
 			},
 			expectedError: "-gitLabProjectID обязателен",
 		},
+		{
+			name: "plan command implies dry run",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"plan"},
+			expectedArgs: Args{
+				Command:              CommandPlan,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DryRun:               true,
+			},
+		},
+		{
+			name: "flags after command",
+			flags: map[string]string{
+				"gitLabToken": "token123",
+			},
+			args: []string{"sync", "-gitLabProjectID", "42", "-dry-run"},
+			expectedArgs: Args{
+				Command:              CommandSync,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "42",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DryRun:               true,
+			},
+		},
+		{
+			name: "adopt with flag names",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"adopt", "-dry-run", "beta_feature", "new_ui"},
+			expectedArgs: Args{
+				Command:              CommandAdopt,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DryRun:               true,
+				FlagNames:            []string{"beta_feature", "new_ui"},
+			},
+		},
+		{
+			name: "plan with output file",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"plan", "-out", "plan.json"},
+			expectedArgs: Args{
+				Command:              CommandPlan,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DryRun:               true,
+				PlanOut:              "plan.json",
+			},
+		},
+		{
+			name: "output file without plan",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+				"out":             "plan.json",
+			},
+			expectedError: "-out используется только с командой plan или -dry-run",
+		},
+		{
+			name: "apply plan file",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"apply", "plan.json"},
+			expectedArgs: Args{
+				Command:              CommandApply,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				PlanFile:             "plan.json",
+			},
+		},
+		{
+			name: "flags after plan file",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"apply", "plan.json", "-continue-on-error"},
+			expectedArgs: Args{
+				Command:              CommandApply,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				ContinueOnError:      true,
+				PlanFile:             "plan.json",
+			},
+		},
+		{
+			name: "flags between flag names",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"adopt", "beta_feature", "-dry-run", "new_ui", "--", "-odd-name"},
+			expectedArgs: Args{
+				Command:              CommandAdopt,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DryRun:               true,
+				FlagNames:            []string{"beta_feature", "new_ui", "-odd-name"},
+			},
+		},
+		{
+			name: "apply without plan file",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args:          []string{"apply"},
+			expectedError: "команде apply нужен ровно один путь к файлу плана",
+		},
+		{
+			name: "apply with selection",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args:          []string{"apply", "-only", "beta_*", "plan.json"},
+			expectedError: "-only нельзя использовать с командой apply",
+		},
+		{
+			name: "drift with report",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args: []string{"drift", "-report", "drift.json"},
+			expectedArgs: Args{
+				Command:              CommandDrift,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DriftReport:          "drift.json",
+			},
+		},
+		{
+			name: "selection patterns and tags",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+				"only":            "team_a_*, team_b_*",
+				"tags":            "checkout",
+			},
+			args: []string{"-exclude", "team_a_legacy", "-exclude", "*_old", "-tags", "payments,ui"},
+			expectedArgs: Args{
+				Command:              CommandSync,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				Only:                 []string{"team_a_*", "team_b_*"},
+				Exclude:              []string{"team_a_legacy", "*_old"},
+				Tags:                 []string{"checkout", "payments", "ui"},
+			},
+		},
+		{
+			name: "invalid pattern",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+				"only":            "team_[a",
+			},
+			expectedError: `некорректный шаблон "team_[a"`,
+		},
+		{
+			name: "unexpected arguments",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args:          []string{"sync", "extra"},
+			expectedError: "неожиданные аргументы: [extra]",
+		},
+		{
+			name: "client certificate without key",
+			flags: map[string]string{
+				"gitLabToken":      "token123",
+				"gitLabProjectID":  "123456",
+				"gitLabClientCert": "client.crt",
+			},
+			expectedError: "-gitLabClientCert и -gitLabClientKey указываются вместе",
+		},
+		{
+			name: "state file does not need GitLab credentials",
+			flags: map[string]string{
+				"stateFile": "state.json",
+			},
+			args: []string{"plan"},
+			expectedArgs: Args{
+				Command:              CommandPlan,
+				FlagsFiles:           []string{defaultFlagsFile},
+				StateFile:            "state.json",
+				GitLabBase:           defaultGitLabBase,
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				DryRun:               true,
+			},
+		},
+		{
+			name:  "validate does not need GitLab credentials",
+			flags: map[string]string{"flagsFile": "flags.yaml"},
+			args:  []string{"validate", "-flagsFile", "teams/", "-flagsFile", "legacy/*.yaml"},
+			expectedArgs: Args{
+				Command:              CommandValidate,
+				FlagsFiles:           []string{"flags.yaml", "teams/", "legacy/*.yaml"},
+				GitLabBase:           defaultGitLabBase,
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+			},
+		},
+		{
+			name: "deletion limit",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+				"max-deletes":     "25%",
+			},
+			expectedArgs: Args{
+				Command:              CommandSync,
+				FlagsFiles:           []string{defaultFlagsFile},
+				GitLabBase:           defaultGitLabBase,
+				GitLabToken:          "token123",
+				GitLabProjectID:      "123456",
+				GitLabRequestTimeout: 10,
+				GitLabRetries:        defaultGitLabRetries,
+				GitLabRetryDelay:     defaultGitLabRetryDelay,
+				GitLabRetryMaxDelay:  defaultGitLabRetryMaxDelay,
+				GitLabRateLimit:      defaultGitLabRateLimit,
+				GitLabRateBurst:      defaultGitLabRateBurst,
+				GitLabPageSize:       defaultGitLabPageSize,
+				GitLabAuth:           defaultGitLabAuth,
+				MaxDeletes:           "25%",
+				DeletionLimit:        &service.DeletionLimit{Value: 25, Percent: true},
+			},
+		},
+		{
+			name: "invalid deletion limit",
+			flags: map[string]string{
+				"max-deletes": "ten",
+			},
+			expectedError: `некорректный -max-deletes: invalid deletion limit "ten"`,
+		},
+		{
+			name: "unknown command",
+			flags: map[string]string{
+				"gitLabToken":     "token123",
+				"gitLabProjectID": "123456",
+			},
+			args:          []string{"destroy"},
+			expectedError: `неизвестная команда "destroy"`,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			resetFlags(t)
+			resetFlags(t, tc.args...)
 			RegisterFlags()
 			for key, value := range tc.flags {
 				err := flag.Set(key, value)
@@ -68,8 +438,88 @@ func TestParseArgs(t *testing.T) {
 	}
 }
 
-// resetFlags сбрасывает флаги между тестами
-func resetFlags(t *testing.T) {
+// resetFlags сбрасывает флаги между тестами и подменяет аргументы командной строки
+func resetFlags(t *testing.T, arguments ...string) {
 	t.Helper()
 	flag.CommandLine = flag.NewFlagSet("", flag.ContinueOnError)
+	args = Args{}
+
+	osArgs := os.Args
+	t.Cleanup(func() { os.Args = osArgs })
+	os.Args = append([]string{osArgs[0]}, arguments...)
+}
+
+func TestTokenSources(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0o600))
+
+	testCases := []struct {
+		name          string
+		flags         map[string]string
+		env           map[string]string
+		expectedError string
+		expectedToken string
+	}{
+		{
+			name:          "token from file",
+			flags:         map[string]string{"gitLabTokenFile": tokenFile},
+			expectedToken: "file-token",
+		},
+		{
+			name:          "token from environment variable",
+			flags:         map[string]string{"gitLabTokenEnv": "FLAGMAN_TOKEN"},
+			env:           map[string]string{"FLAGMAN_TOKEN": "env-token"},
+			expectedToken: "env-token",
+		},
+		{
+			name:          "job token defaults to CI_JOB_TOKEN",
+			flags:         map[string]string{"gitLabAuth": "job"},
+			env:           map[string]string{"CI_JOB_TOKEN": "job-token"},
+			expectedToken: "job-token",
+		},
+		{
+			name:          "empty environment variable",
+			flags:         map[string]string{"gitLabTokenEnv": "FLAGMAN_TOKEN"},
+			env:           map[string]string{"FLAGMAN_TOKEN": ""},
+			expectedError: "-gitLabToken обязателен",
+		},
+		{
+			name:          "missing token file",
+			flags:         map[string]string{"gitLabTokenFile": filepath.Join(t.TempDir(), "missing")},
+			expectedError: "не удалось прочитать -gitLabTokenFile",
+		},
+		{
+			name:          "several token sources",
+			flags:         map[string]string{"gitLabToken": "token123", "gitLabTokenFile": tokenFile},
+			expectedError: "укажите только один из",
+		},
+		{
+			name:          "unknown authentication mode",
+			flags:         map[string]string{"gitLabToken": "token123", "gitLabAuth": "basic"},
+			expectedError: `неизвестный способ аутентификации "basic"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resetFlags(t)
+			RegisterFlags()
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+			require.NoError(t, flag.Set("gitLabProjectID", "123456"))
+			for key, value := range tc.flags {
+				require.NoError(t, flag.Set(key, value))
+			}
+
+			parsedArgs, err := ParseArgs()
+
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedToken, parsedArgs.GitLabToken)
+		})
+	}
 }
@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// File хранит флаги в локальном JSON файле, чтобы репетировать синхронизацию без GitLab.
+// Файл читается и перезаписывается целиком при каждой операции, отсутствующий файл означает пустое состояние.
+type File struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFile создаёт хранилище в файле path
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+func (f *File) GetAllFeatureFlags(ctx context.Context) ([]config.FeatureFlag, error) {
+	var flags []config.FeatureFlag
+	err := f.view(func(m *Memory) (err error) {
+		flags, err = m.GetAllFeatureFlags(ctx)
+		return err
+	})
+	return flags, err
+}
+
+func (f *File) GetFeatureFlag(ctx context.Context, flagName string) (config.FeatureFlag, error) {
+	var flag config.FeatureFlag
+	err := f.view(func(m *Memory) (err error) {
+		flag, err = m.GetFeatureFlag(ctx, flagName)
+		return err
+	})
+	return flag, err
+}
+
+func (f *File) CreateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error {
+	return f.modify(ctx, func(m *Memory) error {
+		return m.CreateFeatureFlag(ctx, flag)
+	})
+}
+
+func (f *File) UpdateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error {
+	return f.modify(ctx, func(m *Memory) error {
+		return m.UpdateFeatureFlag(ctx, flag)
+	})
+}
+
+func (f *File) DeleteFeatureFlag(ctx context.Context, flagName string) error {
+	return f.modify(ctx, func(m *Memory) error {
+		return m.DeleteFeatureFlag(ctx, flagName)
+	})
+}
+
+func (f *File) GetAllUserLists(ctx context.Context) ([]config.UserList, error) {
+	var lists []config.UserList
+	err := f.view(func(m *Memory) (err error) {
+		lists, err = m.GetAllUserLists(ctx)
+		return err
+	})
+	return lists, err
+}
+
+func (f *File) CreateUserList(ctx context.Context, list config.UserList) error {
+	return f.modify(ctx, func(m *Memory) error {
+		return m.CreateUserList(ctx, list)
+	})
+}
+
+func (f *File) UpdateUserList(ctx context.Context, list config.UserList) error {
+	return f.modify(ctx, func(m *Memory) error {
+		return m.UpdateUserList(ctx, list)
+	})
+}
+
+func (f *File) DeleteUserList(ctx context.Context, name string) error {
+	return f.modify(ctx, func(m *Memory) error {
+		return m.DeleteUserList(ctx, name)
+	})
+}
+
+// state содержимое файла состояния
+type state struct {
+	Flags     []config.FeatureFlag `json:"flags"`
+	UserLists []config.UserList    `json:"user_lists"`
+}
+
+// view загружает состояние из файла и передаёт его в action
+func (f *File) view(action func(*Memory) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+	return action(m)
+}
+
+// modify загружает состояние, применяет к нему action и сохраняет результат
+func (f *File) modify(ctx context.Context, action func(*Memory) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+	if err := action(m); err != nil {
+		return err
+	}
+	return f.save(ctx, m)
+}
+
+func (f *File) load() (*Memory, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewMemory(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("error unmarshalling state file %s: %w", f.Path, err)
+	}
+	return NewMemory(st.Flags...).AddUserLists(st.UserLists...), nil
+}
+
+// save атомарно перезаписывает файл, чтобы прерванный запуск не оставил его повреждённым
+func (f *File) save(ctx context.Context, m *Memory) error {
+	var st state
+	var err error
+	if st.Flags, err = m.GetAllFeatureFlags(ctx); err != nil {
+		return err
+	}
+	if st.UserLists, err = m.GetAllUserLists(ctx); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	t.Run("Missing file is empty state", func(t *testing.T) {
+		flags, err := NewFile(path).GetAllFeatureFlags(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, flags)
+	})
+
+	t.Run("Changes persist between instances", func(t *testing.T) {
+		require.NoError(t, NewFile(path).CreateFeatureFlag(ctx, config.FeatureFlag{Name: "flag2", Active: true}))
+		require.NoError(t, NewFile(path).CreateFeatureFlag(ctx, config.FeatureFlag{Name: "flag1"}))
+		require.NoError(t, NewFile(path).UpdateFeatureFlag(ctx, config.FeatureFlag{Name: "flag1", Description: "updated"}))
+
+		flags, err := NewFile(path).GetAllFeatureFlags(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []config.FeatureFlag{
+			{Name: "flag1", Description: "updated", Strategies: []config.Strategy{}},
+			{Name: "flag2", Active: true, Strategies: []config.Strategy{}},
+		}, flags)
+
+		require.NoError(t, NewFile(path).DeleteFeatureFlag(ctx, "flag2"))
+		_, err = NewFile(path).GetFeatureFlag(ctx, "flag2")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Failed operation leaves file untouched", func(t *testing.T) {
+		before, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, NewFile(path).CreateFeatureFlag(ctx, config.FeatureFlag{Name: "flag1"}), ErrExists)
+
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("Invalid file", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(invalid, []byte("{"), 0o644))
+
+		_, err := NewFile(invalid).GetAllFeatureFlags(ctx)
+		assert.ErrorContains(t, err, "error unmarshalling state file")
+	})
+}
+
+func TestFileUserLists(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Lists persist between instances", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, NewFile(path).CreateUserList(ctx, config.UserList{Name: "beta", UserXIDs: []string{"alice"}}))
+
+		lists, err := NewFile(path).GetAllUserLists(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []config.UserList{{Name: "beta", UserXIDs: []string{"alice"}}}, lists)
+	})
+}
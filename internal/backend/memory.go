@@ -0,0 +1,219 @@
+// Package backend содержит хранилища флагов, которые можно использовать вместо GitLab:
+// в памяти для тестов и в локальном JSON файле для репетиций синхронизации.
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/flagstore"
+)
+
+var (
+	// ErrNotFound флаг или список пользователей с таким именем отсутствует
+	ErrNotFound = flagstore.ErrNotFound
+	// ErrExists флаг или список пользователей с таким именем уже существует
+	ErrExists = errors.New("already exists")
+)
+
+// Memory хранит флаги в памяти. Как и GitLab, теги флагов не сохраняются.
+type Memory struct {
+	mu        sync.Mutex
+	flags     map[string]config.FeatureFlag
+	userLists map[string]config.UserList
+}
+
+// NewMemory создаёт хранилище с начальным набором флагов
+func NewMemory(flags ...config.FeatureFlag) *Memory {
+	m := &Memory{
+		flags:     make(map[string]config.FeatureFlag, len(flags)),
+		userLists: make(map[string]config.UserList),
+	}
+	for _, flag := range flags {
+		m.flags[flag.Name] = stored(flag)
+	}
+	return m
+}
+
+// AddUserLists добавляет списки пользователей в начальное состояние
+func (m *Memory) AddUserLists(lists ...config.UserList) *Memory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, list := range lists {
+		m.userLists[list.Name] = storedList(list)
+	}
+	return m
+}
+
+func (m *Memory) GetAllFeatureFlags(ctx context.Context) ([]config.FeatureFlag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flags := make([]config.FeatureFlag, 0, len(m.flags))
+	for _, flag := range m.flags {
+		flags = append(flags, stored(flag))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+func (m *Memory) GetFeatureFlag(ctx context.Context, flagName string) (config.FeatureFlag, error) {
+	if err := ctx.Err(); err != nil {
+		return config.FeatureFlag{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag, ok := m.flags[flagName]
+	if !ok {
+		return config.FeatureFlag{}, fmt.Errorf("failed to get feature flag %s: %w", flagName, ErrNotFound)
+	}
+	return stored(flag), nil
+}
+
+func (m *Memory) CreateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.flags[flag.Name]; ok {
+		return fmt.Errorf("failed to create feature flag %s: %w", flag.Name, ErrExists)
+	}
+	if err := m.checkUserLists(flag); err != nil {
+		return fmt.Errorf("failed to create feature flag %s: %w", flag.Name, err)
+	}
+	m.flags[flag.Name] = stored(flag)
+	return nil
+}
+
+func (m *Memory) UpdateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.flags[flag.Name]; !ok {
+		return fmt.Errorf("failed to update feature flag %s: %w", flag.Name, ErrNotFound)
+	}
+	if err := m.checkUserLists(flag); err != nil {
+		return fmt.Errorf("failed to update feature flag %s: %w", flag.Name, err)
+	}
+	m.flags[flag.Name] = stored(flag)
+	return nil
+}
+
+func (m *Memory) DeleteFeatureFlag(ctx context.Context, flagName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.flags, flagName)
+	return nil
+}
+
+func (m *Memory) GetAllUserLists(ctx context.Context) ([]config.UserList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lists := make([]config.UserList, 0, len(m.userLists))
+	for _, list := range m.userLists {
+		lists = append(lists, storedList(list))
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Name < lists[j].Name })
+	return lists, nil
+}
+
+func (m *Memory) CreateUserList(ctx context.Context, list config.UserList) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.userLists[list.Name]; ok {
+		return fmt.Errorf("failed to create user list %s: %w", list.Name, ErrExists)
+	}
+	m.userLists[list.Name] = storedList(list)
+	return nil
+}
+
+func (m *Memory) UpdateUserList(ctx context.Context, list config.UserList) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.userLists[list.Name]; !ok {
+		return fmt.Errorf("failed to update user list %s: %w", list.Name, ErrNotFound)
+	}
+	m.userLists[list.Name] = storedList(list)
+	return nil
+}
+
+// DeleteUserList как и GitLab, отказывается удалять список, на который ссылается стратегия
+func (m *Memory) DeleteUserList(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, flag := range m.flags {
+		for _, strategy := range flag.Strategies {
+			if strategy.UserList == name {
+				return fmt.Errorf("failed to delete user list %s: used by feature flag %s", name, flag.Name)
+			}
+		}
+	}
+	delete(m.userLists, name)
+	return nil
+}
+
+// checkUserLists проверяет, что списки пользователей, на которые ссылается флаг, существуют
+func (m *Memory) checkUserLists(flag config.FeatureFlag) error {
+	for _, strategy := range flag.Strategies {
+		if _, ok := m.userLists[strategy.UserList]; strategy.UserList != "" && !ok {
+			return fmt.Errorf("user list %q referenced by feature flag %s: %w", strategy.UserList, flag.Name, ErrNotFound)
+		}
+	}
+	return nil
+}
+
+func storedList(list config.UserList) config.UserList {
+	list.UserXIDs = slices.Clone(list.UserXIDs)
+	if list.UserXIDs == nil {
+		list.UserXIDs = []string{}
+	}
+	return list
+}
+
+// stored копия флага в том виде, в котором его хранит GitLab: без тегов и без общих с вызывающим срезов и карт
+func stored(flag config.FeatureFlag) config.FeatureFlag {
+	flag.Tags = nil
+	strategies := make([]config.Strategy, 0, len(flag.Strategies))
+	for _, strategy := range flag.Strategies {
+		strategy.Parameters = maps.Clone(strategy.Parameters)
+		strategy.Scopes = slices.Clone(strategy.Scopes)
+		strategies = append(strategies, strategy)
+	}
+	flag.Strategies = strategies
+	return flag
+}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	flag := config.FeatureFlag{
+		Name:   "flag1",
+		Active: true,
+		Tags:   []string{"checkout"},
+		Strategies: []config.Strategy{
+			{Name: "default", Parameters: map[string]interface{}{}, Scopes: []config.Scope{{Environment: "*"}}},
+		},
+	}
+
+	m := NewMemory()
+	require.NoError(t, m.CreateFeatureFlag(ctx, flag))
+	assert.ErrorIs(t, m.CreateFeatureFlag(ctx, flag), ErrExists)
+
+	t.Run("Stores a copy without tags", func(t *testing.T) {
+		flag.Strategies[0].Scopes[0].Environment = "production"
+
+		stored, err := m.GetFeatureFlag(ctx, "flag1")
+		require.NoError(t, err)
+		assert.Nil(t, stored.Tags)
+		assert.Equal(t, "*", stored.Strategies[0].Scopes[0].Environment)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		require.NoError(t, m.UpdateFeatureFlag(ctx, config.FeatureFlag{Name: "flag1", Description: "updated"}))
+		stored, err := m.GetFeatureFlag(ctx, "flag1")
+		require.NoError(t, err)
+		assert.Equal(t, "updated", stored.Description)
+
+		assert.ErrorIs(t, m.UpdateFeatureFlag(ctx, config.FeatureFlag{Name: "missing"}), ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, m.DeleteFeatureFlag(ctx, "flag1"))
+		require.NoError(t, m.DeleteFeatureFlag(ctx, "flag1"), "deleting a missing flag is not an error")
+
+		_, err := m.GetFeatureFlag(ctx, "flag1")
+		assert.ErrorIs(t, err, ErrNotFound)
+		flags, err := m.GetAllFeatureFlags(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, flags)
+	})
+
+	t.Run("Canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := m.GetAllFeatureFlags(canceled)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestMemoryUserLists(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	flag := config.FeatureFlag{Name: "flag1", Strategies: []config.Strategy{{Name: "gitlabUserList", UserList: "beta"}}}
+	assert.ErrorIs(t, m.CreateFeatureFlag(ctx, flag), ErrNotFound, "flag cannot reference a missing list")
+
+	require.NoError(t, m.CreateUserList(ctx, config.UserList{Name: "beta", UserXIDs: []string{"alice"}}))
+	assert.ErrorIs(t, m.CreateUserList(ctx, config.UserList{Name: "beta"}), ErrExists)
+	require.NoError(t, m.UpdateUserList(ctx, config.UserList{Name: "beta", UserXIDs: []string{"alice", "bob"}}))
+	require.NoError(t, m.CreateFeatureFlag(ctx, flag))
+
+	lists, err := m.GetAllUserLists(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []config.UserList{{Name: "beta", UserXIDs: []string{"alice", "bob"}}}, lists)
+
+	assert.ErrorContains(t, m.DeleteUserList(ctx, "beta"), "used by feature flag flag1")
+	require.NoError(t, m.DeleteFeatureFlag(ctx, "flag1"))
+	require.NoError(t, m.DeleteUserList(ctx, "beta"))
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Authenticator добавляет к запросу данные для аутентификации в GitLab.
+// Клиент вызывает его для каждого запроса, поэтому методы не выставляют заголовки сами.
+type Authenticator interface {
+	Authenticate(req *http.Request)
+}
+
+// PrivateToken personal или project access token, передаётся в заголовке Private-Token
+type PrivateToken string
+
+func (t PrivateToken) Authenticate(req *http.Request) {
+	req.Header.Set("Private-Token", string(t))
+}
+
+// JobToken токен CI задачи (CI_JOB_TOKEN), передаётся в заголовке JOB-TOKEN
+type JobToken string
+
+func (t JobToken) Authenticate(req *http.Request) {
+	req.Header.Set("JOB-TOKEN", string(t))
+}
+
+// OAuthToken OAuth 2.0 токен, передаётся в заголовке Authorization: Bearer
+type OAuthToken string
+
+func (t OAuthToken) Authenticate(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+}
+
+// Способы аутентификации, которые можно выбрать по имени
+const (
+	AuthPrivateToken = "private" // personal access token
+	AuthProjectToken = "project" // project access token, передаётся так же, как personal
+	AuthJobToken     = "job"     // CI_JOB_TOKEN
+	AuthOAuth        = "oauth"   // OAuth 2.0 Bearer токен
+)
+
+// NewAuthenticator создаёт Authenticator для способа аутентификации mode
+func NewAuthenticator(mode, token string) (Authenticator, error) {
+	switch mode {
+	case AuthPrivateToken, AuthProjectToken:
+		return PrivateToken(token), nil
+	case AuthJobToken:
+		return JobToken(token), nil
+	case AuthOAuth:
+		return OAuthToken(token), nil
+	default:
+		return nil, fmt.Errorf("unknown authentication mode %q", mode)
+	}
+}
+
+// WithAuthenticator задаёт способ аутентификации вместо Private-Token из поля Token
+func WithAuthenticator(auth Authenticator) Option {
+	return func(c *GitLabClient) {
+		c.auth = auth
+	}
+}
+
+func (c *GitLabClient) authenticate(req *http.Request) {
+	if c.auth != nil {
+		c.auth.Authenticate(req)
+		return
+	}
+	PrivateToken(c.Token).Authenticate(req)
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticators(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mode   string
+		header string
+		value  string
+	}{
+		{name: "Personal access token", mode: AuthPrivateToken, header: "Private-Token", value: "secret"},
+		{name: "Project access token", mode: AuthProjectToken, header: "Private-Token", value: "secret"},
+		{name: "CI job token", mode: AuthJobToken, header: "JOB-TOKEN", value: "secret"},
+		{name: "OAuth token", mode: AuthOAuth, header: "Authorization", value: "Bearer secret"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var headers http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers = r.Header.Clone()
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			auth, err := NewAuthenticator(tc.mode, "secret")
+			require.NoError(t, err)
+			c := NewGitLabClient(server.URL, "", "1", 5, WithAuthenticator(auth))
+
+			require.NoError(t, c.DeleteFeatureFlag(context.Background(), "flag1"))
+			assert.Equal(t, tc.value, headers.Get(tc.header))
+			for _, other := range []string{"Private-Token", "JOB-TOKEN", "Authorization"} {
+				if other != tc.header {
+					assert.Empty(t, headers.Get(other), "unexpected %s header", other)
+				}
+			}
+		})
+	}
+
+	t.Run("Token field is used without authenticator", func(t *testing.T) {
+		var token string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token = r.Header.Get("Private-Token")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		c := NewGitLabClient(server.URL, "personal", "1", 5)
+		require.NoError(t, c.DeleteFeatureFlag(context.Background(), "flag1"))
+		assert.Equal(t, "personal", token)
+	})
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		_, err := NewAuthenticator("basic", "secret")
+		assert.ErrorContains(t, err, `unknown authentication mode "basic"`)
+	})
+}
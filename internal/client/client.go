@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"net/http"
+	"net/url"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/nkrus/gitlab-flagman/config"
@@ -18,9 +21,38 @@ type GitLabClient struct {
 	Token      string
 	ProjectID  string
 	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *rateLimiter
+	pageSize   int
+	auth       Authenticator
+}
+
+// Option настраивает GitLabClient при создании
+type Option func(*GitLabClient)
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *GitLabClient) {
+		c.retry = policy
+	}
+}
+
+// WithRateLimit ограничивает число запросов в секунду для всех методов клиента.
+// requestsPerSecond <= 0 снимает ограничение, замедление по заголовкам GitLab сохраняется.
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(c *GitLabClient) {
+		c.limiter = newRateLimiter(requestsPerSecond, burst)
+	}
+}
+
+// WithPageSize задаёт число флагов на странице при получении списка, не больше 100
+func WithPageSize(size int) Option {
+	return func(c *GitLabClient) {
+		c.pageSize = size
+	}
 }
 
 type Pagination struct {
+	nextLink   string
 	page       int
 	nextPage   int
 	prevPage   int
@@ -29,19 +61,27 @@ type Pagination struct {
 	total      int
 }
 
-func NewGitLabClient(baseURL, token, projectID string, requestTimeout int) *GitLabClient {
-	return &GitLabClient{
+func NewGitLabClient(baseURL, token, projectID string, requestTimeout int, opts ...Option) *GitLabClient {
+	c := &GitLabClient{
 		BaseURL:   baseURL,
 		Token:     token,
 		ProjectID: projectID,
 		httpClient: &http.Client{
 			Timeout: time.Duration(requestTimeout) * time.Second,
 		},
+		retry:    DefaultRetryPolicy,
+		limiter:  newRateLimiter(0, 1),
+		pageSize: maxPerPage,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 const maxConcurrency = 5
-const maxPerPage = 2
+const maxPerPage = 100                    // Максимальный размер страницы, который принимает GitLab.
+const linkHeader = "Link"                 // Ссылки на соседние страницы, rel="next" указывает на следующую.
 const xPageHeader = "X-Page"              // The index of the current page (starting at 1).
 const xNextPageHeader = "X-Next-Page"     // The index of the next page.
 const xPrevPageHeader = "X-Prev-Page"     // The index of the previous page.
@@ -50,73 +90,200 @@ const xTotalPagesHeader = "X-Total-Pages" // The total number of pages.
 const xTotalHeader = "X-Total"            // The total number of items.
 
 func (c *GitLabClient) GetAllFeatureFlags(ctx context.Context) ([]config.FeatureFlag, error) {
-	_, pagination, err := c.getFeatureFlagsWithPagination(ctx, 1, maxPerPage)
-	if err != nil {
-		return nil, err
+	var allFeatureFlags []config.FeatureFlag
+	for flag, err := range c.FeatureFlags(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		allFeatureFlags = append(allFeatureFlags, flag)
 	}
-	// Канал для передачи результатов
-	results := make(chan []config.FeatureFlag, pagination.totalPages)
-	errors := make(chan error, pagination.totalPages)
+	return allFeatureFlags, nil
+}
 
-	// Семафор для ограничения числа горутин
-	semaphore := make(chan struct{}, maxConcurrency)
+// GetAllRemoteFeatureFlags возвращает все флаги проекта вместе с ID, версиями и датами
+func (c *GitLabClient) GetAllRemoteFeatureFlags(ctx context.Context) ([]RemoteFeatureFlag, error) {
+	var allFeatureFlags []RemoteFeatureFlag
+	for flag, err := range c.RemoteFeatureFlags(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		allFeatureFlags = append(allFeatureFlags, flag)
+	}
+	return allFeatureFlags, nil
+}
 
-	var wg sync.WaitGroup
-	// Запускаем горутины для загрузки всех страниц
-	for page := 1; page <= pagination.totalPages; page++ {
-		wg.Add(1)
-		go func(ctx context.Context, page int) {
-			defer wg.Done()
-			featureFlags, _, err := c.getFeatureFlagsWithPagination(ctx, page, maxPerPage)
+// FeatureFlags перебирает все флаги проекта в модели конфигурации, см. RemoteFeatureFlags
+func (c *GitLabClient) FeatureFlags(ctx context.Context) iter.Seq2[config.FeatureFlag, error] {
+	return func(yield func(config.FeatureFlag, error) bool) {
+		for flag, err := range c.RemoteFeatureFlags(ctx) {
 			if err != nil {
-				errors <- err
+				yield(config.FeatureFlag{}, err)
 				return
 			}
-			// Ограничиваем количество одновременных запросов
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			if !yield(flag.ToConfig(), nil) {
+				return
+			}
+		}
+	}
+}
 
-			results <- featureFlags
-		}(ctx, page)
+// RemoteFeatureFlags перебирает все флаги проекта, загружая страницы по мере необходимости.
+// Следующая страница определяется по заголовку Link или X-Next-Page, поэтому перебор
+// не зависит от X-Total-Pages, который GitLab не отдаёт для больших коллекций.
+// При первой ошибке итератор возвращает её и прекращает работу, отменяя загрузку остальных страниц.
+func (c *GitLabClient) RemoteFeatureFlags(ctx context.Context) iter.Seq2[RemoteFeatureFlag, error] {
+	return func(yield func(RemoteFeatureFlag, error) bool) {
+		for page, err := range c.featureFlagPages(ctx) {
+			if err != nil {
+				yield(RemoteFeatureFlag{}, err)
+				return
+			}
+			for _, flag := range page {
+				if !yield(flag, nil) {
+					return
+				}
+			}
+		}
 	}
+}
 
-	// Закрываем канал результатов, когда все горутины завершатся
-	go func() {
-		wg.Wait()
-		close(results)
-		close(errors)
-	}()
+// featureFlagPages перебирает страницы флагов по порядку.
+// Если GitLab сообщил число страниц, оставшиеся страницы загружаются параллельно,
+// после последней из них перебор продолжается по ссылкам на случай, если флагов стало больше.
+func (c *GitLabClient) featureFlagPages(ctx context.Context) iter.Seq2[[]RemoteFeatureFlag, error] {
+	return func(yield func([]RemoteFeatureFlag, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		endpoint := c.featureFlagsPageURL(1)
+		visited := make(map[string]bool)
+		for endpoint != "" {
+			if visited[endpoint] {
+				yield(nil, fmt.Errorf("failed to get feature flags: pagination loops back to %s", endpoint))
+				return
+			}
+			visited[endpoint] = true
 
-	// Собираем все результаты
-	var allFeatureFlags []config.FeatureFlag
-	for result := range results {
-		allFeatureFlags = append(allFeatureFlags, result...)
+			featureFlags, pagination, err := c.getFeatureFlagsPage(ctx, endpoint)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(featureFlags, nil) {
+				return
+			}
+
+			if pagination.page == 1 && pagination.totalPages > 1 {
+				last, ok := c.fetchPagesConcurrently(ctx, 2, pagination.totalPages, yield)
+				if !ok {
+					return
+				}
+				for page := 2; page <= pagination.totalPages; page++ {
+					visited[c.featureFlagsPageURL(page)] = true
+				}
+				pagination = last
+			}
+
+			endpoint, err = c.nextPageURL(pagination)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// fetchPagesConcurrently загружает страницы from..to не более чем в maxConcurrency запросов
+// и передаёт их в yield по порядку. Возвращает пагинацию последней страницы.
+func (c *GitLabClient) fetchPagesConcurrently(ctx context.Context, from, to int, yield func([]RemoteFeatureFlag, error) bool) (Pagination, bool) {
+	type pageResult struct {
+		featureFlags []RemoteFeatureFlag
+		pagination   Pagination
+		err          error
+	}
+
+	// Семафор для ограничения числа одновременных запросов
+	semaphore := make(chan struct{}, maxConcurrency)
+	results := make([]chan pageResult, 0, to-from+1)
+	for page := from; page <= to; page++ {
+		result := make(chan pageResult, 1)
+		results = append(results, result)
+		go func() {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				result <- pageResult{err: fmt.Errorf("failed to get feature flags: %w", ctx.Err())}
+				return
+			}
+			defer func() { <-semaphore }()
+
+			featureFlags, pagination, err := c.getFeatureFlagsPage(ctx, c.featureFlagsPageURL(page))
+			result <- pageResult{featureFlags: featureFlags, pagination: pagination, err: err}
+		}()
 	}
 
-	// Проверяем, были ли ошибки
-	if len(errors) > 0 {
-		err := <-errors
-		return nil, err
+	var last Pagination
+	for _, result := range results {
+		r := <-result
+		if r.err != nil {
+			yield(nil, r.err)
+			return Pagination{}, false
+		}
+		if !yield(r.featureFlags, nil) {
+			return Pagination{}, false
+		}
+		last = r.pagination
 	}
+	return last, true
+}
 
-	return allFeatureFlags, err
+func (c *GitLabClient) featureFlagsPageURL(page int) string {
+	return fmt.Sprintf("%s?page=%d&per_page=%d", c.featureFlagsURL(), page, c.perPage())
 }
 
-func (c *GitLabClient) getFeatureFlagsWithPagination(ctx context.Context, page, perPage int) ([]config.FeatureFlag, Pagination, error) {
-	endpoint := fmt.Sprintf("%s/projects/%s/feature_flags?page=%d&per_page=%d", c.BaseURL, c.ProjectID, page, perPage)
+func (c *GitLabClient) perPage() int {
+	if c.pageSize <= 0 || c.pageSize > maxPerPage {
+		return maxPerPage
+	}
+	return c.pageSize
+}
+
+// nextPageURL адрес следующей страницы или пустая строка, если страница последняя.
+// Ссылке из заголовка Link доверяем только на том же хосте, что и BaseURL, чтобы не отправить токен на чужой сервер.
+func (c *GitLabClient) nextPageURL(pagination Pagination) (string, error) {
+	if pagination.nextLink != "" {
+		next, err := url.Parse(pagination.nextLink)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", linkHeader, err)
+		}
+		base, err := url.Parse(c.BaseURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse base URL: %w", err)
+		}
+		if next.Scheme != base.Scheme || next.Host != base.Host {
+			return "", fmt.Errorf("refusing to follow next page link to %s outside of %s", next.Redacted(), c.BaseURL)
+		}
+		return next.String(), nil
+	}
+	if pagination.nextPage > 0 {
+		return c.featureFlagsPageURL(pagination.nextPage), nil
+	}
+	return "", nil
+}
+
+func (c *GitLabClient) getFeatureFlagsPage(ctx context.Context, endpoint string) ([]RemoteFeatureFlag, Pagination, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, Pagination{}, fmt.Errorf("failed to create GET request: %w", err)
 	}
-	req.Header.Set("Private-Token", c.Token)
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, nil)
 	if err != nil {
 		return nil, Pagination{}, fmt.Errorf("failed to get feature flags: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, Pagination{}, fmt.Errorf("failed to get feature flags: %s", resp.Status)
+		return nil, Pagination{}, fmt.Errorf("failed to get feature flags: %w", newAPIError(resp))
 	}
 
 	pagination, err := getPagination(resp)
@@ -124,13 +291,29 @@ func (c *GitLabClient) getFeatureFlagsWithPagination(ctx context.Context, page,
 		return nil, Pagination{}, err
 	}
 
-	var featureFlags []config.FeatureFlag
+	var featureFlags []RemoteFeatureFlag
 	if err := json.NewDecoder(resp.Body).Decode(&featureFlags); err != nil {
 		return nil, pagination, fmt.Errorf("failed to decode feature flags response: %w", err)
 	}
 	return featureFlags, pagination, nil
 }
 
+// parseNextLink извлекает адрес с rel="next" из заголовка Link
+func parseNextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
 func getPagination(resp *http.Response) (Pagination, error) {
 	parseHeader := func(header string) (int, error) {
 		if header == "" {
@@ -170,6 +353,7 @@ func getPagination(resp *http.Response) (Pagination, error) {
 	}
 
 	return Pagination{
+		nextLink:   parseNextLink(resp.Header.Get(linkHeader)),
 		page:       page,
 		nextPage:   nextPage,
 		prevPage:   prevPage,
@@ -180,30 +364,35 @@ func getPagination(resp *http.Response) (Pagination, error) {
 }
 
 func (c *GitLabClient) DeleteFeatureFlag(ctx context.Context, flagName string) error {
-	deleteURL := fmt.Sprintf("%s/projects/%s/feature_flags/%s", c.BaseURL, c.ProjectID, flagName)
+	deleteURL := c.featureFlagURL(flagName)
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
 	if err != nil {
 		return fmt.Errorf("error creating DELETE request: %w", err)
 	}
-	req.Header.Set("Private-Token", c.Token)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, nil)
 	if err != nil {
 		return fmt.Errorf("error deleting feature flag %s: %w", flagName, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
-		return fmt.Errorf("error deleting feature flag %s: %s", flagName, resp.Status)
+		return fmt.Errorf("error deleting feature flag %s: %w", flagName, newAPIError(resp))
 	}
 
 	return nil
 }
 
 func (c *GitLabClient) CreateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error {
-	createURL := fmt.Sprintf("%s/projects/%s/feature_flags", c.BaseURL, c.ProjectID)
+	createURL := c.featureFlagsURL()
+
+	userListIDs, err := c.userListIDs(ctx, flag)
+	if err != nil {
+		return fmt.Errorf("failed to create feature flag %s: %w", flag.Name, err)
+	}
 
-	data, err := json.Marshal(flag)
+	// Теги используются только gitlab-flagman и в запрос не попадают
+	data, err := json.Marshal(buildCreateRequest(flag, userListIDs))
 	if err != nil {
 		return fmt.Errorf("failed to marshal feature flag: %w", err)
 	}
@@ -212,18 +401,216 @@ func (c *GitLabClient) CreateFeatureFlag(ctx context.Context, flag config.Featur
 	if err != nil {
 		return fmt.Errorf("failed to create POST request: %w", err)
 	}
-	req.Header.Set("Private-Token", c.Token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	// Предыдущая попытка могла создать флаг, несмотря на таймаут или 5xx, поэтому
+	// перед повтором проверяем, не существует ли он уже
+	resp, err := c.do(req, func(ctx context.Context) (bool, error) {
+		return c.featureFlagExists(ctx, flag.Name)
+	})
+	if errors.Is(err, errAlreadyApplied) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create feature flag %s: %w", flag.Name, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create feature flag %s: %s", flag.Name, resp.Status)
+		return fmt.Errorf("failed to create feature flag %s: %w", flag.Name, newAPIError(resp))
+	}
+
+	return nil
+}
+
+// buildCreateRequest тело запроса POST /projects/:id/feature_flags, списки пользователей передаются по ID
+func buildCreateRequest(flag config.FeatureFlag, userListIDs map[string]int) updateFeatureFlagRequest {
+	request := updateFeatureFlagRequest{
+		Name:        flag.Name,
+		Description: flag.Description,
+		Active:      flag.Active,
+	}
+	if flag.Strategies != nil {
+		request.Strategies = make([]interface{}, 0, len(flag.Strategies))
+	}
+	for _, strategy := range flag.Strategies {
+		payload := updateStrategy{
+			Name:       strategy.Name,
+			Parameters: config.NormalizeParameters(strategy.Parameters),
+			Scopes:     make([]updateScope, 0, len(strategy.Scopes)),
+			UserListID: userListIDs[strategy.UserList],
+		}
+		for _, scope := range strategy.Scopes {
+			payload.Scopes = append(payload.Scopes, updateScope{Environment: scope.Environment})
+		}
+		request.Strategies = append(request.Strategies, payload)
+	}
+	return request
+}
+
+// Тело запроса PUT /projects/:id/feature_flags/:name.
+// Стратегии и окружения сопоставляются по ID, удаляемые помечаются _destroy.
+type updateFeatureFlagRequest struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Active      bool          `json:"active"`
+	Strategies  []interface{} `json:"strategies"`
+}
+
+// updateStrategy стратегия в теле запроса. Параметры передаются строками, других GitLab не принимает.
+type updateStrategy struct {
+	ID         int               `json:"id,omitempty"`
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters"`
+	Scopes     []updateScope     `json:"scopes"`
+	UserListID int               `json:"user_list_id,omitempty"`
+}
+
+type destroyStrategy struct {
+	ID      int  `json:"id"`
+	Destroy bool `json:"_destroy"`
+}
+
+type updateScope struct {
+	ID          int    `json:"id,omitempty"`
+	Environment string `json:"environment_scope,omitempty"`
+	Destroy     bool   `json:"_destroy,omitempty"`
+}
+
+// GetRemoteFeatureFlag возвращает флаг по имени вместе с ID стратегий и окружений, версией и датами
+func (c *GitLabClient) GetRemoteFeatureFlag(ctx context.Context, flagName string) (RemoteFeatureFlag, error) {
+	getURL := c.featureFlagURL(flagName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
+	if err != nil {
+		return RemoteFeatureFlag{}, fmt.Errorf("failed to create GET request: %w", err)
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return RemoteFeatureFlag{}, fmt.Errorf("failed to get feature flag %s: %w", flagName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return RemoteFeatureFlag{}, fmt.Errorf("failed to get feature flag %s: %w", flagName, newAPIError(resp))
+	}
+
+	var flag RemoteFeatureFlag
+	if err := json.NewDecoder(resp.Body).Decode(&flag); err != nil {
+		return RemoteFeatureFlag{}, fmt.Errorf("failed to decode feature flag %s: %w", flagName, err)
+	}
+	return flag, nil
+}
+
+func (c *GitLabClient) featureFlagExists(ctx context.Context, flagName string) (bool, error) {
+	_, err := c.GetRemoteFeatureFlag(ctx, flagName)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.NotFound() {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetFeatureFlag возвращает флаг по имени, для отсутствующего флага ошибка содержит APIError 404
+func (c *GitLabClient) GetFeatureFlag(ctx context.Context, flagName string) (config.FeatureFlag, error) {
+	remote, err := c.GetRemoteFeatureFlag(ctx, flagName)
+	if err != nil {
+		return config.FeatureFlag{}, err
+	}
+	return remote.ToConfig(), nil
+}
+
+// UpdateFeatureFlag изменяет существующий флаг на месте, сохраняя его IID и историю в GitLab
+func (c *GitLabClient) UpdateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error {
+	remote, err := c.GetRemoteFeatureFlag(ctx, flag.Name)
+	if err != nil {
+		return err
+	}
+	userListIDs, err := c.userListIDs(ctx, flag)
+	if err != nil {
+		return fmt.Errorf("failed to update feature flag %s: %w", flag.Name, err)
+	}
+
+	data, err := json.Marshal(buildUpdateRequest(remote, flag, userListIDs))
+	if err != nil {
+		return fmt.Errorf("failed to marshal feature flag: %w", err)
+	}
+
+	updateURL := c.featureFlagURL(flag.Name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, updateURL, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("failed to create PUT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update feature flag %s: %w", flag.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update feature flag %s: %w", flag.Name, newAPIError(resp))
 	}
 
 	return nil
 }
+
+// buildUpdateRequest сопоставляет стратегии флага с удалёнными по имени, а окружения - по environment_scope.
+// Несопоставленные удалённые стратегии и окружения помечаются на удаление.
+func buildUpdateRequest(remote RemoteFeatureFlag, flag config.FeatureFlag, userListIDs map[string]int) updateFeatureFlagRequest {
+	request := updateFeatureFlagRequest{
+		Name:        flag.Name,
+		Description: flag.Description,
+		Active:      flag.Active,
+		Strategies:  make([]interface{}, 0, len(flag.Strategies)),
+	}
+
+	used := make([]bool, len(remote.Strategies))
+	for _, strategy := range flag.Strategies {
+		payload := updateStrategy{
+			Name:       strategy.Name,
+			Parameters: config.NormalizeParameters(strategy.Parameters),
+			Scopes:     make([]updateScope, 0, len(strategy.Scopes)),
+			UserListID: userListIDs[strategy.UserList],
+		}
+
+		var remoteScopes []RemoteScope
+		for i, rs := range remote.Strategies {
+			if !used[i] && rs.Name == strategy.Name {
+				used[i] = true
+				payload.ID = rs.ID
+				remoteScopes = rs.Scopes
+				break
+			}
+		}
+
+		scopeIDs := make(map[string]int, len(remoteScopes))
+		for _, scope := range remoteScopes {
+			scopeIDs[scope.EnvironmentScope] = scope.ID
+		}
+		kept := make(map[string]bool, len(strategy.Scopes))
+		for _, scope := range strategy.Scopes {
+			kept[scope.Environment] = true
+			payload.Scopes = append(payload.Scopes, updateScope{
+				ID:          scopeIDs[scope.Environment],
+				Environment: scope.Environment,
+			})
+		}
+		for _, scope := range remoteScopes {
+			if !kept[scope.EnvironmentScope] {
+				payload.Scopes = append(payload.Scopes, updateScope{ID: scope.ID, Destroy: true})
+			}
+		}
+
+		request.Strategies = append(request.Strategies, payload)
+	}
+
+	for i, rs := range remote.Strategies {
+		if !used[i] {
+			request.Strategies = append(request.Strategies, destroyStrategy{ID: rs.ID, Destroy: true})
+		}
+	}
+
+	return request
+}
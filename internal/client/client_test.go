@@ -3,8 +3,11 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -31,8 +34,8 @@ func TestGetExistingFeatureFlags(t *testing.T) {
 				t.Fatalf("Failed to marshal test flags: %v", err)
 			}
 			w.Header().Set(xPageHeader, "1")
-			w.Header().Set(xNextPageHeader, "")
-			w.Header().Set(xPrevPageHeader, "1")
+			w.Header().Set(xNextPageHeader, "2")
+			w.Header().Set(xPrevPageHeader, "")
 			w.Header().Set(xPerPageHeader, "2")
 			w.Header().Set(xTotalPagesHeader, "2")
 			w.Header().Set(xTotalHeader, "4")
@@ -48,8 +51,8 @@ func TestGetExistingFeatureFlags(t *testing.T) {
 				t.Fatalf("Failed to marshal test flags: %v", err)
 			}
 			w.Header().Set(xPageHeader, "2")
-			w.Header().Set(xNextPageHeader, "2")
-			w.Header().Set(xPrevPageHeader, "")
+			w.Header().Set(xNextPageHeader, "")
+			w.Header().Set(xPrevPageHeader, "1")
 			w.Header().Set(xPerPageHeader, "2")
 			w.Header().Set(xTotalPagesHeader, "2")
 			w.Header().Set(xTotalHeader, "4")
@@ -70,6 +73,7 @@ func TestGetExistingFeatureFlags(t *testing.T) {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		pageSize: 2,
 	}
 
 	t.Run("success", func(t *testing.T) {
@@ -146,6 +150,140 @@ func TestGetExistingFeatureFlags(t *testing.T) {
 	})
 }
 
+// pagedServer отдаёт флаги страницами по параметру page, заголовки пагинации задаёт headers
+func pagedServer(t *testing.T, pages [][]string, headers func(w http.ResponseWriter, page int)) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		require.NoError(t, err)
+		require.True(t, page >= 1 && page <= len(pages), "unexpected page %d", page)
+
+		flags := make([]config.FeatureFlag, 0, len(pages[page-1]))
+		for _, name := range pages[page-1] {
+			flags = append(flags, config.FeatureFlag{Name: name})
+		}
+		headers(w, page)
+		require.NoError(t, json.NewEncoder(w).Encode(flags))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func collectNames(c *GitLabClient) ([]string, error) {
+	var names []string
+	for flag, err := range c.FeatureFlags(context.Background()) {
+		if err != nil {
+			return names, err
+		}
+		names = append(names, flag.Name)
+	}
+	return names, nil
+}
+
+func TestFeatureFlagsPagination(t *testing.T) {
+	pages := [][]string{{"flag1", "flag2"}, {"flag3", "flag4"}, {"flag5"}}
+	expected := []string{"flag1", "flag2", "flag3", "flag4", "flag5"}
+
+	t.Run("Follows X-Next-Page without X-Total-Pages", func(t *testing.T) {
+		server, requests := pagedServer(t, pages, func(w http.ResponseWriter, page int) {
+			w.Header().Set(xPageHeader, strconv.Itoa(page))
+			if page < len(pages) {
+				w.Header().Set(xNextPageHeader, strconv.Itoa(page+1))
+			}
+		})
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+
+		names, err := collectNames(c)
+		require.NoError(t, err)
+		assert.Equal(t, expected, names)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("Follows Link header", func(t *testing.T) {
+		var server *httptest.Server
+		server, requests := pagedServer(t, pages, func(w http.ResponseWriter, page int) {
+			if page < len(pages) {
+				next := fmt.Sprintf("%s/projects/1/feature_flags?page=%d&per_page=100", server.URL, page+1)
+				w.Header().Set(linkHeader, fmt.Sprintf(`<%s/projects/1/feature_flags?page=1>; rel="first", <%s>; rel="next"`, server.URL, next))
+			}
+		})
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+
+		names, err := collectNames(c)
+		require.NoError(t, err)
+		assert.Equal(t, expected, names)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("Fetches known pages concurrently in order", func(t *testing.T) {
+		server, requests := pagedServer(t, pages, func(w http.ResponseWriter, page int) {
+			w.Header().Set(xPageHeader, strconv.Itoa(page))
+			w.Header().Set(xTotalPagesHeader, strconv.Itoa(len(pages)))
+			if page < len(pages) {
+				w.Header().Set(xNextPageHeader, strconv.Itoa(page+1))
+			}
+		})
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}), WithPageSize(2))
+
+		names, err := collectNames(c)
+		require.NoError(t, err)
+		assert.Equal(t, expected, names)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("Stops on first error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "2" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set(xPageHeader, r.URL.Query().Get("page"))
+			w.Header().Set(xTotalPagesHeader, "10")
+			require.NoError(t, json.NewEncoder(w).Encode([]config.FeatureFlag{{Name: "flag" + r.URL.Query().Get("page")}}))
+		}))
+		defer server.Close()
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+
+		names, err := collectNames(c)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "403")
+		assert.Equal(t, []string{"flag1"}, names)
+	})
+
+	t.Run("Stops fetching when consumer stops", func(t *testing.T) {
+		server, requests := pagedServer(t, pages, func(w http.ResponseWriter, page int) {
+			w.Header().Set(xNextPageHeader, strconv.Itoa(page+1))
+		})
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+
+		for range c.FeatureFlags(context.Background()) {
+			break
+		}
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("Refuses next link to another host", func(t *testing.T) {
+		server, _ := pagedServer(t, pages, func(w http.ResponseWriter, page int) {
+			w.Header().Set(linkHeader, `<https://attacker.example/projects/1/feature_flags?page=2>; rel="next"`)
+		})
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+
+		_, err := collectNames(c)
+		assert.ErrorContains(t, err, "refusing to follow next page link")
+	})
+
+	t.Run("Detects pagination loop", func(t *testing.T) {
+		server, _ := pagedServer(t, pages, func(w http.ResponseWriter, page int) {
+			w.Header().Set(xNextPageHeader, "1")
+		})
+		c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+
+		_, err := collectNames(c)
+		assert.ErrorContains(t, err, "pagination loops back")
+	})
+}
+
 func TestDeleteFeatureFlag(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "DELETE", r.Method)
@@ -264,6 +402,29 @@ func TestCreateFeatureFlag(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("parameters_sent_as_strings", func(t *testing.T) {
+		flag := config.FeatureFlag{Name: "test-flag", Active: true, Strategies: []config.Strategy{
+			{Name: "gradualRolloutUserId", Parameters: map[string]interface{}{"percentage": 50, "groupId": "default"}, Scopes: []config.Scope{{Environment: "*"}}},
+			{Name: "userWithId", Parameters: map[string]interface{}{"userIds": []interface{}{2, "1"}}, Scopes: []config.Scope{{Environment: "*"}}},
+		}}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Strategies []struct {
+					Parameters map[string]interface{} `json:"parameters"`
+				} `json:"strategies"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Len(t, body.Strategies, 2)
+			assert.Equal(t, map[string]interface{}{"percentage": "50", "groupId": "default"}, body.Strategies[0].Parameters)
+			assert.Equal(t, map[string]interface{}{"userIds": "1,2"}, body.Strategies[1].Parameters)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10)
+		assert.NoError(t, client.CreateFeatureFlag(context.Background(), flag))
+	})
+
 	t.Run("request_creation_error", func(t *testing.T) {
 		client := &GitLabClient{
 			BaseURL:   "://invalid-url",
@@ -311,3 +472,120 @@ func TestCreateFeatureFlag(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to create feature flag test-flag: 400 Bad Request")
 	})
 }
+
+func TestUpdateFeatureFlag(t *testing.T) {
+	remoteFlag := `{
+		"name": "test-flag",
+		"description": "old",
+		"active": true,
+		"strategies": [
+			{"id": 10, "name": "default", "parameters": {}, "scopes": [
+				{"id": 100, "environment_scope": "PROD"},
+				{"id": 101, "environment_scope": "TEST"}
+			]},
+			{"id": 11, "name": "userWithId", "parameters": {"userIds": "1"}, "scopes": [
+				{"id": 102, "environment_scope": "*"}
+			]}
+		]
+	}`
+	flag := config.FeatureFlag{
+		Name:        "test-flag",
+		Description: "new",
+		Active:      false,
+		Strategies: []config.Strategy{
+			{
+				Name:       "default",
+				Parameters: map[string]interface{}{},
+				Scopes:     []config.Scope{{Environment: "PROD"}, {Environment: "STAGE"}},
+			},
+			{
+				Name:       "gradualRolloutUserId",
+				Parameters: map[string]interface{}{"percentage": 50, "groupId": "default"},
+				Scopes:     []config.Scope{{Environment: "*"}},
+			},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/projects/1/feature_flags/test-flag", r.URL.Path)
+			assert.NotEmpty(t, r.Header.Get("Private-Token"), "Private-Token header must be present")
+
+			switch r.Method {
+			case http.MethodGet:
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(remoteFlag))
+				require.NoError(t, err)
+			case http.MethodPut:
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				expected := map[string]interface{}{
+					"name":        "test-flag",
+					"description": "new",
+					"active":      false,
+					"strategies": []interface{}{
+						map[string]interface{}{
+							"id":         float64(10),
+							"name":       "default",
+							"parameters": map[string]interface{}{},
+							"scopes": []interface{}{
+								map[string]interface{}{"id": float64(100), "environment_scope": "PROD"},
+								map[string]interface{}{"environment_scope": "STAGE"},
+								map[string]interface{}{"id": float64(101), "_destroy": true},
+							},
+						},
+						map[string]interface{}{
+							"name":       "gradualRolloutUserId",
+							"parameters": map[string]interface{}{"percentage": "50", "groupId": "default"},
+							"scopes": []interface{}{
+								map[string]interface{}{"environment_scope": "*"},
+							},
+						},
+						map[string]interface{}{"id": float64(11), "_destroy": true},
+					},
+				}
+				assert.Equal(t, expected, body)
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Errorf("unexpected method %s", r.Method)
+			}
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10)
+		err := client.UpdateFeatureFlag(context.Background(), flag)
+		assert.NoError(t, err)
+	})
+
+	t.Run("flag_not_found", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10)
+		err := client.UpdateFeatureFlag(context.Background(), flag)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get feature flag test-flag: 404")
+	})
+
+	t.Run("server_error_status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(remoteFlag))
+				require.NoError(t, err)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10)
+		err := client.UpdateFeatureFlag(context.Background(), flag)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to update feature flag test-flag: 400 Bad Request")
+	})
+}
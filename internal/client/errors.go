@@ -0,0 +1,133 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/internal/flagstore"
+)
+
+const requestIDHeader = "X-Request-Id" // ID запроса в логах GitLab.
+
+// maxErrorBodySize ограничивает чтение тела ответа с ошибкой
+const maxErrorBodySize = 64 << 10
+
+// APIError ответ GitLab с неожиданным HTTP статусом вместе с разобранным сообщением об ошибке
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	// Message сообщение GitLab из полей message или error
+	Message string
+	// FieldErrors ошибки проверки по полям, если GitLab вернул их в message
+	FieldErrors map[string][]string
+	RequestID   string
+}
+
+// newAPIError читает тело ответа и разбирает сообщение GitLab
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RequestID:  resp.Header.Get(requestIDHeader),
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.URL = resp.Request.URL.Redacted()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err == nil {
+		apiErr.parseBody(body)
+	}
+	return apiErr
+}
+
+// parseBody понимает форматы ошибок GitLab:
+// {"message": "..."}, {"message": ["..."]}, {"message": {"field": ["..."]}} и {"error": "...", "error_description": "..."}
+func (e *APIError) parseBody(body []byte) {
+	var payload struct {
+		Message          json.RawMessage `json:"message"`
+		Error            string          `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return
+	}
+
+	var message string
+	var messages []string
+	var fields map[string][]string
+	switch {
+	case json.Unmarshal(payload.Message, &message) == nil:
+		e.Message = message
+	case json.Unmarshal(payload.Message, &messages) == nil:
+		e.Message = strings.Join(messages, "; ")
+	case json.Unmarshal(payload.Message, &fields) == nil:
+		e.FieldErrors = fields
+	case payload.ErrorDescription != "":
+		e.Message = payload.ErrorDescription
+	default:
+		e.Message = payload.Error
+	}
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Status)
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if len(e.FieldErrors) > 0 {
+		fields := make([]string, 0, len(e.FieldErrors))
+		for field := range e.FieldErrors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for i, field := range fields {
+			sep := ", "
+			if i == 0 {
+				sep = ": "
+			}
+			fmt.Fprintf(&b, "%s%s %s", sep, field, strings.Join(e.FieldErrors[field], ", "))
+		}
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request ID %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+// Validation GitLab отклонил данные запроса
+func (e *APIError) Validation() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+}
+
+// Unauthorized токен недействителен, отозван или истёк
+func (e *APIError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// Forbidden у токена нет прав на конкретную операцию
+func (e *APIError) Forbidden() bool {
+	return e.StatusCode == http.StatusForbidden
+}
+
+func (e *APIError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// Is сопоставляет ответ 404 с flagstore.ErrNotFound, как у остальных хранилищ флагов
+func (e *APIError) Is(target error) bool {
+	return target == flagstore.ErrNotFound && e.NotFound()
+}
+
+// RateLimited лимит запросов исчерпан и повторы не помогли
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/flagstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIError(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		body        string
+		message     string
+		fieldErrors map[string][]string
+		errString   string
+	}{
+		{
+			name:      "String message",
+			status:    http.StatusBadRequest,
+			body:      `{"message":"Strategies parameters are invalid"}`,
+			message:   "Strategies parameters are invalid",
+			errString: "400 Bad Request: Strategies parameters are invalid (request ID req-1)",
+		},
+		{
+			name:      "List of messages",
+			status:    http.StatusBadRequest,
+			body:      `{"message":["Name is invalid","Version is invalid"]}`,
+			message:   "Name is invalid; Version is invalid",
+			errString: "400 Bad Request: Name is invalid; Version is invalid (request ID req-1)",
+		},
+		{
+			name:   "Field errors",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"message":{"strategies.parameters":["must be valid"],"name":["has already been taken"]}}`,
+			fieldErrors: map[string][]string{
+				"strategies.parameters": {"must be valid"},
+				"name":                  {"has already been taken"},
+			},
+			errString: "422 Unprocessable Entity: name has already been taken, strategies.parameters must be valid (request ID req-1)",
+		},
+		{
+			name:      "OAuth error",
+			status:    http.StatusUnauthorized,
+			body:      `{"error":"invalid_token","error_description":"Token is expired."}`,
+			message:   "Token is expired.",
+			errString: "401 Unauthorized: Token is expired. (request ID req-1)",
+		},
+		{
+			name:      "Not JSON",
+			status:    http.StatusBadGateway,
+			body:      `<html>Bad Gateway</html>`,
+			errString: "502 Bad Gateway (request ID req-1)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-1")
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+			err := c.CreateFeatureFlag(context.Background(), config.FeatureFlag{Name: "flag1"})
+
+			var apiErr *APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, http.MethodPost, apiErr.Method)
+			assert.Equal(t, server.URL+"/projects/1/feature_flags", apiErr.URL)
+			assert.Equal(t, tc.status, apiErr.StatusCode)
+			assert.Equal(t, tc.message, apiErr.Message)
+			assert.Equal(t, tc.fieldErrors, apiErr.FieldErrors)
+			assert.Equal(t, "req-1", apiErr.RequestID)
+			assert.Equal(t, tc.errString, apiErr.Error())
+		})
+	}
+}
+
+func TestAPIErrorKinds(t *testing.T) {
+	testCases := []struct {
+		status       int
+		validation   bool
+		unauthorized bool
+		forbidden    bool
+		notFound     bool
+		rateLimited  bool
+	}{
+		{status: http.StatusBadRequest, validation: true},
+		{status: http.StatusUnprocessableEntity, validation: true},
+		{status: http.StatusUnauthorized, unauthorized: true},
+		{status: http.StatusForbidden, forbidden: true},
+		{status: http.StatusNotFound, notFound: true},
+		{status: http.StatusTooManyRequests, rateLimited: true},
+		{status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			err := &APIError{StatusCode: tc.status}
+			assert.Equal(t, tc.validation, err.Validation())
+			assert.Equal(t, tc.unauthorized, err.Unauthorized())
+			assert.Equal(t, tc.forbidden, err.Forbidden())
+			assert.Equal(t, tc.notFound, err.NotFound())
+			assert.Equal(t, tc.rateLimited, err.RateLimited())
+			assert.Equal(t, tc.notFound, errors.Is(fmt.Errorf("wrapped: %w", err), flagstore.ErrNotFound))
+		})
+	}
+}
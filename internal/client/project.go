@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Project проект GitLab, с флагами которого работает клиент
+type Project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	// FeatureFlagsAccessLevel disabled, private или enabled, GitLab 16.0+
+	FeatureFlagsAccessLevel string `json:"feature_flags_access_level"`
+	// OperationsAccessLevel включал флаги в более старых версиях GitLab
+	OperationsAccessLevel string `json:"operations_access_level"`
+}
+
+const accessLevelDisabled = "disabled"
+
+func (p Project) featureFlagsDisabled() bool {
+	if p.FeatureFlagsAccessLevel != "" {
+		return p.FeatureFlagsAccessLevel == accessLevelDisabled
+	}
+	return p.OperationsAccessLevel == accessLevelDisabled
+}
+
+// projectURL адрес проекта, ProjectID может быть числом или путём group/subgroup/project
+func (c *GitLabClient) projectURL() string {
+	return fmt.Sprintf("%s/projects/%s", c.BaseURL, url.PathEscape(c.ProjectID))
+}
+
+func (c *GitLabClient) featureFlagsURL() string {
+	return c.projectURL() + "/feature_flags"
+}
+
+func (c *GitLabClient) featureFlagURL(flagName string) string {
+	return c.featureFlagsURL() + "/" + url.PathEscape(flagName)
+}
+
+// ResolveProject проверяет, что проект существует и в нём включены флаги,
+// и заменяет путь проекта в ProjectID его числовым ID.
+func (c *GitLabClient) ResolveProject(ctx context.Context) (Project, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.projectURL(), nil)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to create GET request: %w", err)
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to get project %s: %w", c.ProjectID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Project{}, fmt.Errorf("project %q not found or not accessible with this token: %w", c.ProjectID, newAPIError(resp))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Project{}, fmt.Errorf("failed to get project %s: %w", c.ProjectID, newAPIError(resp))
+	}
+
+	var project Project
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return Project{}, fmt.Errorf("failed to decode project %s: %w", c.ProjectID, err)
+	}
+	if project.ID == 0 {
+		return Project{}, errors.New("failed to get project: response has no project ID")
+	}
+	if project.featureFlagsDisabled() {
+		return Project{}, fmt.Errorf("feature flags are disabled in project %s, enable them in Settings > General > Visibility, project features, permissions", project.PathWithNamespace)
+	}
+
+	c.ProjectID = strconv.Itoa(project.ID)
+	return project, nil
+}
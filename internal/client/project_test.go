@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLEscaping(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		switch r.Method {
+		case http.MethodGet:
+			require.NoError(t, json.NewEncoder(w).Encode(RemoteFeatureFlag{Name: "a/b c"}))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	c := NewGitLabClient(server.URL, "token", "group/sub group/project", 5, WithRetryPolicy(RetryPolicy{}))
+	require.NoError(t, c.DeleteFeatureFlag(context.Background(), "a/b c"))
+	require.NoError(t, c.UpdateFeatureFlag(context.Background(), config.FeatureFlag{Name: "a/b c"}))
+
+	assert.Equal(t, []string{
+		"DELETE /projects/group%2Fsub%20group%2Fproject/feature_flags/a%2Fb%20c",
+		"GET /projects/group%2Fsub%20group%2Fproject/feature_flags/a%2Fb%20c",
+		"PUT /projects/group%2Fsub%20group%2Fproject/feature_flags/a%2Fb%20c",
+	}, paths)
+}
+
+func TestResolveProject(t *testing.T) {
+	projects := map[string]Project{
+		"/projects/group%2Fproject":  {ID: 42, PathWithNamespace: "group/project", FeatureFlagsAccessLevel: "enabled"},
+		"/projects/group%2Fdisabled": {ID: 43, PathWithNamespace: "group/disabled", FeatureFlagsAccessLevel: "disabled"},
+		"/projects/group%2Flegacy":   {ID: 44, PathWithNamespace: "group/legacy", OperationsAccessLevel: "disabled"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		project, ok := projects[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(project))
+	}))
+	defer server.Close()
+
+	t.Run("Resolves path to numeric ID", func(t *testing.T) {
+		c := NewGitLabClient(server.URL, "token", "group/project", 5, WithRetryPolicy(RetryPolicy{}))
+		project, err := c.ResolveProject(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 42, project.ID)
+		assert.Equal(t, "42", c.ProjectID)
+	})
+
+	t.Run("Missing project", func(t *testing.T) {
+		c := NewGitLabClient(server.URL, "token", "group/missing", 5, WithRetryPolicy(RetryPolicy{}))
+		_, err := c.ResolveProject(context.Background())
+		assert.ErrorContains(t, err, `project "group/missing" not found`)
+		assert.Equal(t, "group/missing", c.ProjectID)
+	})
+
+	t.Run("Feature flags disabled", func(t *testing.T) {
+		for _, path := range []string{"group/disabled", "group/legacy"} {
+			c := NewGitLabClient(server.URL, "token", path, 5, WithRetryPolicy(RetryPolicy{}))
+			_, err := c.ResolveProject(context.Background())
+			assert.ErrorContains(t, err, "feature flags are disabled in project "+path)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const rateLimitRemainingHeader = "RateLimit-Remaining" // Число запросов, оставшихся до сброса лимита.
+
+// rateLimiter token bucket, через который проходят все запросы клиента.
+// Помимо заданной скорости он замедляется по заголовкам RateLimit-Remaining/RateLimit-Reset:
+// оставшиеся запросы равномерно распределяются до момента сброса лимита.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // запросов в секунду, 0 - без ограничения
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	// Ограничение, выставленное по заголовкам GitLab, действует до slowUntil
+	slowRate  float64
+	slowUntil time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	b := float64(max(burst, 1))
+	return &rateLimiter{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// effectiveRate текущая скорость с учётом замедления по заголовкам
+func (l *rateLimiter) effectiveRate(now time.Time) float64 {
+	if now.Before(l.slowUntil) && (l.rate <= 0 || l.slowRate < l.rate) {
+		return l.slowRate
+	}
+	return l.rate
+}
+
+// Wait резервирует токен и ждёт, пока он станет доступен
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	wait := l.reserve(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	return sleep(ctx, wait)
+}
+
+// reserve резервирует токен в момент now и возвращает, сколько нужно подождать до его появления
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rate := l.effectiveRate(now)
+
+	var wait time.Duration
+	switch {
+	case now.Before(l.slowUntil) && rate == 0:
+		// Лимит исчерпан, ждём его сброса
+		wait = l.slowUntil.Sub(now)
+	case rate > 0:
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*rate)
+		l.tokens--
+		if l.tokens < 0 {
+			wait = time.Duration(-l.tokens / rate * float64(time.Second))
+		}
+	}
+	l.last = now
+	return wait
+}
+
+// observe подстраивает скорость под заголовки ответа GitLab
+func (l *rateLimiter) observe(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get(rateLimitRemainingHeader))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(rateLimitResetHeader), 10, 64)
+	if err != nil {
+		return
+	}
+	resetAt := time.Unix(reset, 0)
+	window := time.Until(resetAt).Seconds()
+	if window <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.slowRate = max(float64(remaining), 0) / window
+	l.slowUntil = resetAt
+	if l.tokens > 1 && l.effectiveRate(time.Now()) == l.slowRate {
+		// Не позволяем накопленному запасу обойти замедление
+		l.tokens = 1
+	}
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Now()
+	limiter := newRateLimiter(50, 1)
+	limiter.last = start
+
+	// Первый запрос проходит сразу, каждый следующий в ту же секунду ждёт ещё 20ms
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, time.Duration(i)*20*time.Millisecond, limiter.reserve(start), "request %d", i)
+	}
+	// Через 200ms очередь разошлась и токен снова есть
+	assert.Zero(t, limiter.reserve(start.Add(200*time.Millisecond)))
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(0, 1)
+
+	for i := 0; i < 100; i++ {
+		assert.Zero(t, limiter.reserve(now))
+	}
+}
+
+func TestRateLimiterObserve(t *testing.T) {
+	resetAt := time.Now().Add(10 * time.Second)
+	reset := strconv.FormatInt(resetAt.Unix(), 10)
+
+	t.Run("Slows down to remaining requests", func(t *testing.T) {
+		limiter := newRateLimiter(100, 10)
+		limiter.observe(rateLimitResponse("5", reset))
+
+		rate := limiter.effectiveRate(time.Now())
+		assert.Greater(t, rate, 0.0)
+		assert.LessOrEqual(t, rate, 1.0, "expected rate of at most 1 request per second")
+		assert.LessOrEqual(t, limiter.tokens, 1.0, "expected burst to be dropped")
+	})
+
+	t.Run("Keeps lower configured rate", func(t *testing.T) {
+		limiter := newRateLimiter(0.1, 1)
+		limiter.observe(rateLimitResponse("1000", reset))
+
+		assert.Equal(t, 0.1, limiter.effectiveRate(time.Now()))
+	})
+
+	t.Run("Waits for reset when exhausted", func(t *testing.T) {
+		limiter := newRateLimiter(0, 1)
+		limiter.observe(rateLimitResponse("0", reset))
+
+		now := time.Now()
+		assert.Equal(t, limiter.slowUntil.Sub(now), limiter.reserve(now))
+		assert.True(t, limiter.slowUntil.Equal(time.Unix(resetAt.Unix(), 0)))
+	})
+
+	t.Run("Ignores responses without headers", func(t *testing.T) {
+		limiter := newRateLimiter(0, 1)
+		limiter.observe(&http.Response{Header: http.Header{}})
+
+		assert.Zero(t, limiter.effectiveRate(time.Now()))
+	})
+}
+
+func rateLimitResponse(remaining, reset string) *http.Response {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set(rateLimitRemainingHeader, remaining)
+	resp.Header.Set(rateLimitResetHeader, reset)
+	return resp
+}
+
+func TestClientRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := NewGitLabClient(server.URL, "token", "1", 5, WithRateLimit(50, 1))
+
+	// Ожидание может только затянуться, поэтому проверяется лишь нижняя граница
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, c.DeleteFeatureFlag(context.Background(), "flag"))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond, "expected client requests to pass through the limiter")
+	assert.Equal(t, int32(5), requests.Load())
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"time"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// RemoteFeatureFlag флаг в том виде, в котором его возвращает API GitLab.
+// В отличие от config.FeatureFlag хранит версию, даты и ID стратегий и окружений,
+// по которым GitLab сопоставляет их при изменении флага.
+type RemoteFeatureFlag struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Active      bool             `json:"active"`
+	Version     string           `json:"version"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Strategies  []RemoteStrategy `json:"strategies"`
+}
+
+type RemoteStrategy struct {
+	ID         int                    `json:"id"`
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Scopes     []RemoteScope          `json:"scopes"`
+	// UserList список пользователей стратегии gitlabUserList
+	UserList *RemoteUserList `json:"user_list,omitempty"`
+}
+
+type RemoteScope struct {
+	ID               int    `json:"id"`
+	EnvironmentScope string `json:"environment_scope"`
+}
+
+// ToConfig переводит флаг в модель конфигурации. ID, версия и даты отбрасываются,
+// список пользователей заменяется его именем.
+func (f RemoteFeatureFlag) ToConfig() config.FeatureFlag {
+	flag := config.FeatureFlag{
+		Name:        f.Name,
+		Description: f.Description,
+		Active:      f.Active,
+		Strategies:  make([]config.Strategy, 0, len(f.Strategies)),
+	}
+	for _, strategy := range f.Strategies {
+		scopes := make([]config.Scope, 0, len(strategy.Scopes))
+		for _, scope := range strategy.Scopes {
+			scopes = append(scopes, config.Scope{Environment: scope.EnvironmentScope})
+		}
+		converted := config.Strategy{
+			Name:       strategy.Name,
+			Parameters: strategy.Parameters,
+			Scopes:     scopes,
+		}
+		if strategy.UserList != nil {
+			converted.UserList = strategy.UserList.Name
+		}
+		flag.Strategies = append(flag.Strategies, converted)
+	}
+	return flag
+}
+
+// RemoteFeatureFlagFromConfig переводит флаг из конфигурации в модель API.
+// ID, версия и даты остаются пустыми, список пользователей заполняется только именем.
+// Теги в GitLab не хранятся и отбрасываются.
+func RemoteFeatureFlagFromConfig(flag config.FeatureFlag) RemoteFeatureFlag {
+	remote := RemoteFeatureFlag{
+		Name:        flag.Name,
+		Description: flag.Description,
+		Active:      flag.Active,
+	}
+	if flag.Strategies != nil {
+		remote.Strategies = make([]RemoteStrategy, 0, len(flag.Strategies))
+	}
+	for _, strategy := range flag.Strategies {
+		converted := RemoteStrategy{
+			Name:       strategy.Name,
+			Parameters: strategy.Parameters,
+			Scopes:     make([]RemoteScope, 0, len(strategy.Scopes)),
+		}
+		for _, scope := range strategy.Scopes {
+			converted.Scopes = append(converted.Scopes, RemoteScope{EnvironmentScope: scope.Environment})
+		}
+		if strategy.UserList != "" {
+			converted.UserList = &RemoteUserList{Name: strategy.UserList}
+		}
+		remote.Strategies = append(remote.Strategies, converted)
+	}
+	return remote
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Ответ GET /projects/:id/feature_flags из документации GitLab
+const remoteFeatureFlagsPayload = `[
+  {
+    "name": "merge_train",
+    "description": "This feature is about merge train",
+    "active": true,
+    "version": "new_version_flag",
+    "created_at": "2019-11-04T08:13:51.423Z",
+    "updated_at": "2019-11-04T08:13:51.423Z",
+    "scopes": [],
+    "strategies": [
+      {
+        "id": 1,
+        "name": "userWithId",
+        "parameters": {"userIds": "user1"},
+        "scopes": [{"id": 1, "environment_scope": "production"}],
+        "user_list": null
+      },
+      {
+        "id": 2,
+        "name": "gitlabUserList",
+        "parameters": {},
+        "scopes": [{"id": 2, "environment_scope": "*"}],
+        "user_list": {"id": 5, "iid": 1, "name": "beta", "user_xids": "user1,user2"}
+      }
+    ]
+  }
+]`
+
+func TestRemoteFeatureFlags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total-Pages", "1")
+		_, _ = w.Write([]byte(remoteFeatureFlagsPayload))
+	}))
+	defer server.Close()
+
+	c := NewGitLabClient(server.URL, "token", "1", 5)
+	flags, err := c.GetAllRemoteFeatureFlags(context.Background())
+	require.NoError(t, err)
+	require.Len(t, flags, 1)
+
+	flag := flags[0]
+	created := time.Date(2019, 11, 4, 8, 13, 51, 423000000, time.UTC)
+	assert.Equal(t, "new_version_flag", flag.Version)
+	assert.True(t, created.Equal(flag.CreatedAt))
+	assert.True(t, created.Equal(flag.UpdatedAt))
+	require.Len(t, flag.Strategies, 2)
+	assert.Equal(t, 1, flag.Strategies[0].ID)
+	assert.Equal(t, []RemoteScope{{ID: 1, EnvironmentScope: "production"}}, flag.Strategies[0].Scopes)
+	assert.Nil(t, flag.Strategies[0].UserList)
+	require.NotNil(t, flag.Strategies[1].UserList)
+	assert.Equal(t, 1, flag.Strategies[1].UserList.IID)
+
+	assert.Equal(t, config.FeatureFlag{
+		Name:        "merge_train",
+		Description: "This feature is about merge train",
+		Active:      true,
+		Strategies: []config.Strategy{
+			{
+				Name:       "userWithId",
+				Parameters: map[string]interface{}{"userIds": "user1"},
+				Scopes:     []config.Scope{{Environment: "production"}},
+			},
+			{
+				Name:       "gitlabUserList",
+				Parameters: map[string]interface{}{},
+				Scopes:     []config.Scope{{Environment: "*"}},
+				UserList:   "beta",
+			},
+		},
+	}, flag.ToConfig())
+}
+
+func TestRemoteFeatureFlagFromConfig(t *testing.T) {
+	flag := config.FeatureFlag{
+		Name:        "flag1",
+		Description: "desc",
+		Active:      true,
+		Tags:        []string{"team-a"},
+		Strategies: []config.Strategy{
+			{
+				Name:       "default",
+				Parameters: map[string]interface{}{},
+				Scopes:     []config.Scope{{Environment: "*"}},
+			},
+			{
+				Name:       "gitlabUserList",
+				Parameters: map[string]interface{}{},
+				Scopes:     []config.Scope{{Environment: "production"}},
+				UserList:   "beta",
+			},
+		},
+	}
+
+	remote := RemoteFeatureFlagFromConfig(flag)
+	assert.Zero(t, remote.Strategies[0].ID)
+	assert.Equal(t, &RemoteUserList{Name: "beta"}, remote.Strategies[1].UserList)
+
+	flag.Tags = nil
+	assert.Equal(t, flag, remote.ToConfig())
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy настройки повторов запросов к GitLab
+type RetryPolicy struct {
+	// MaxRetries число повторов после первой попытки, 0 - без повторов
+	MaxRetries int
+	// BaseDelay задержка перед первым повтором, далее удваивается
+	BaseDelay time.Duration
+	// MaxDelay верхняя граница задержки между повторами. Если GitLab просит подождать дольше,
+	// запрос не повторяется.
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+const retryAfterHeader = "Retry-After"         // Секунды или HTTP дата, после которой можно повторить запрос.
+const rateLimitResetHeader = "RateLimit-Reset" // Unix время сброса лимита запросов.
+
+// errAlreadyApplied возвращается do, если проверка перед повтором показала, что запрос уже выполнен
+var errAlreadyApplied = errors.New("request already applied")
+
+// beforeRetryFunc вызывается перед повтором неидемпотентного запроса.
+// Если она возвращает true, предыдущая попытка уже применилась на стороне GitLab.
+type beforeRetryFunc func(ctx context.Context) (bool, error)
+
+// do выполняет запрос через общий rateLimiter, повторяя его при сетевых ошибках, 429 и 5xx согласно RetryPolicy.
+// Тело запроса должно поддерживать GetBody (bytes.Buffer, bytes.Reader, strings.Reader).
+func (c *GitLabClient) do(req *http.Request, beforeRetry beforeRetryFunc) (*http.Response, error) {
+	ctx := req.Context()
+	c.authenticate(req)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if resp != nil && c.limiter != nil {
+			c.limiter.observe(resp)
+		}
+		if attempt >= c.retry.MaxRetries || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := c.retry.backoff(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			if serverDelay, ok := retryDelay(resp); ok {
+				// Ждать дольше MaxDelay не имеет смысла: вызывающий получит ответ GitLab как APIError
+				if c.retry.MaxDelay > 0 && serverDelay > c.retry.MaxDelay {
+					log.Printf("Not retrying %s %s: GitLab asked to wait %s, longer than the maximum delay %s",
+						req.Method, req.URL.Redacted(), serverDelay.Round(time.Second), c.retry.MaxDelay)
+					return resp, nil
+				}
+				delay = serverDelay
+			}
+			reason = resp.Status
+			resp.Body.Close()
+		}
+		log.Printf("Retrying %s %s in %s (retry %d/%d): %s", req.Method, req.URL.Redacted(), delay.Round(time.Millisecond), attempt+1, c.retry.MaxRetries, reason)
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		if beforeRetry != nil {
+			applied, err := beforeRetry(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check result of previous attempt: %w", err)
+			}
+			if applied {
+				return nil, errAlreadyApplied
+			}
+		}
+	}
+}
+
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff экспоненциальная задержка со случайным разбросом в диапазоне [d/2, d]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay << min(attempt, 30)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryDelay читает задержку, запрошенную GitLab в Retry-After или, для 429, в RateLimit-Reset
+func retryDelay(resp *http.Response) (time.Duration, bool) {
+	if value := resp.Header.Get(retryAfterHeader); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(time.Until(date), 0), true
+		}
+	}
+	if value := resp.Header.Get(rateLimitResetHeader); value != "" && resp.StatusCode == http.StatusTooManyRequests {
+		if reset, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), true
+		}
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetries = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetries(t *testing.T) {
+	t.Run("retries_server_errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10, WithRetryPolicy(fastRetries))
+		err := client.DeleteFeatureFlag(context.Background(), "flag1")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("gives_up_after_max_retries", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10, WithRetryPolicy(fastRetries))
+		err := client.DeleteFeatureFlag(context.Background(), "flag1")
+		assert.ErrorContains(t, err, "error deleting feature flag flag1: 502")
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("does_not_retry_client_errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10, WithRetryPolicy(fastRetries))
+		err := client.CreateFeatureFlag(context.Background(), config.FeatureFlag{Name: "flag1"})
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("resends_request_body", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var flag config.FeatureFlag
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&flag))
+			assert.Equal(t, "flag1", flag.Name)
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10, WithRetryPolicy(fastRetries))
+		err := client.CreateFeatureFlag(context.Background(), config.FeatureFlag{Name: "flag1"})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("create_is_not_repeated_after_timeout", func(t *testing.T) {
+		var created atomic.Bool
+		var posts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost:
+				posts.Add(1)
+				created.Store(true)
+				time.Sleep(1500 * time.Millisecond) // ответ не успевает до таймаута клиента
+				w.WriteHeader(http.StatusCreated)
+			case http.MethodGet:
+				if !created.Load() {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, err := w.Write([]byte(`{"name": "flag1"}`))
+				require.NoError(t, err)
+			}
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 1, WithRetryPolicy(fastRetries))
+		err := client.CreateFeatureFlag(context.Background(), config.FeatureFlag{Name: "flag1"})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), posts.Load())
+	})
+
+	t.Run("request_canceled_during_backoff", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(retryAfterHeader, "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		policy := fastRetries
+		policy.MaxDelay = 2 * time.Minute
+		client := NewGitLabClient(server.URL, "some-token", "1", 10, WithRetryPolicy(policy))
+		err := client.DeleteFeatureFlag(ctx, "flag1")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("server_delay_longer_than_max_delay", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.Header().Set(retryAfterHeader, "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := NewGitLabClient(server.URL, "some-token", "1", 10, WithRetryPolicy(fastRetries))
+		start := time.Now()
+		err := client.DeleteFeatureFlag(context.Background(), "flag1")
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.True(t, apiErr.RateLimited())
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	response := func(status int, headers map[string]string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		for key, value := range headers {
+			resp.Header.Set(key, value)
+		}
+		return resp
+	}
+
+	delay, ok := retryDelay(response(http.StatusTooManyRequests, map[string]string{retryAfterHeader: "7"}))
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, delay)
+
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	delay, ok = retryDelay(response(http.StatusServiceUnavailable, map[string]string{retryAfterHeader: date}))
+	assert.True(t, ok)
+	assert.InDelta(t, 10*time.Second, delay, float64(2*time.Second))
+
+	reset := strconv.FormatInt(time.Now().Add(5*time.Second).Unix(), 10)
+	delay, ok = retryDelay(response(http.StatusTooManyRequests, map[string]string{rateLimitResetHeader: reset}))
+	assert.True(t, ok)
+	assert.InDelta(t, 5*time.Second, delay, float64(2*time.Second))
+
+	_, ok = retryDelay(response(http.StatusServiceUnavailable, map[string]string{rateLimitResetHeader: reset}))
+	assert.False(t, ok, "RateLimit-Reset applies to 429 responses only")
+
+	_, ok = retryDelay(response(http.StatusTooManyRequests, nil))
+	assert.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, upper := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		delay := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, upper*time.Millisecond/2)
+		assert.LessOrEqual(t, delay, upper*time.Millisecond)
+	}
+	delay := policy.backoff(100)
+	assert.GreaterOrEqual(t, delay, time.Second/2, "large attempts must not overflow")
+	assert.LessOrEqual(t, delay, time.Second)
+	assert.Zero(t, RetryPolicy{}.backoff(1))
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportConfig настройки TLS и прокси для соединения с GitLab
+type TransportConfig struct {
+	// CAFile PEM файл с сертификатами, которым доверяем в дополнение к системным
+	CAFile string
+	// CertFile и KeyFile клиентский сертификат и ключ для mutual TLS
+	CertFile string
+	KeyFile  string
+	// ProxyURL адрес HTTP(S) прокси, без него используются HTTPS_PROXY, HTTP_PROXY и NO_PROXY
+	ProxyURL string
+	// InsecureSkipVerify отключает проверку сертификата GitLab, только для тестовых стендов
+	InsecureSkipVerify bool
+}
+
+// NewTransport создаёт http.Transport по настройкам TransportConfig
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.InsecureSkipVerify {
+		log.Println("WARNING: TLS certificate verification is disabled, GitLab's identity is not checked and the token can be intercepted. Use only in lab setups.")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+// WithTransport задаёт транспорт HTTP клиента, например созданный NewTransport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *GitLabClient) {
+		c.httpClient.Transport = transport
+	}
+}
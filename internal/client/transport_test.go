@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM сохраняет PEM блок во временный файл и возвращает путь к нему
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// clientCertificate создаёт самоподписанный клиентский сертификат и возвращает пути к сертификату и ключу
+func clientCertificate(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gitlab-flagman"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return cert, writePEM(t, "client.crt", "CERTIFICATE", der), writePEM(t, "client.key", "EC PRIVATE KEY", keyDER)
+}
+
+func deleteThrough(t *testing.T, baseURL string, cfg TransportConfig) error {
+	t.Helper()
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	c := NewGitLabClient(baseURL, "token", "1", 5, WithTransport(transport), WithRetryPolicy(RetryPolicy{}))
+	return c.DeleteFeatureFlag(context.Background(), "flag1")
+}
+
+func TestTransportTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	caFile := writePEM(t, "ca.crt", "CERTIFICATE", server.Certificate().Raw)
+
+	t.Run("Rejects unknown CA", func(t *testing.T) {
+		err := deleteThrough(t, server.URL, TransportConfig{})
+		assert.ErrorContains(t, err, "certificate")
+	})
+
+	t.Run("Trusts custom CA bundle", func(t *testing.T) {
+		assert.NoError(t, deleteThrough(t, server.URL, TransportConfig{CAFile: caFile}))
+	})
+
+	t.Run("Skips verification when insecure", func(t *testing.T) {
+		assert.NoError(t, deleteThrough(t, server.URL, TransportConfig{InsecureSkipVerify: true}))
+	})
+
+	t.Run("Invalid CA bundle", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.crt")
+		require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+		_, err := NewTransport(TransportConfig{CAFile: path})
+		assert.ErrorContains(t, err, "no PEM certificates found")
+	})
+}
+
+func TestTransportMutualTLS(t *testing.T) {
+	cert, certFile, keyFile := clientCertificate(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caFile := writePEM(t, "ca.crt", "CERTIFICATE", server.Certificate().Raw)
+
+	t.Run("Without client certificate", func(t *testing.T) {
+		assert.Error(t, deleteThrough(t, server.URL, TransportConfig{CAFile: caFile}))
+	})
+
+	t.Run("With client certificate", func(t *testing.T) {
+		assert.NoError(t, deleteThrough(t, server.URL, TransportConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}))
+	})
+
+	t.Run("Certificate without key", func(t *testing.T) {
+		_, err := NewTransport(TransportConfig{CertFile: certFile})
+		assert.ErrorContains(t, err, "must be set together")
+	})
+}
+
+func TestTransportProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	t.Run("Explicit proxy", func(t *testing.T) {
+		proxied = ""
+		require.NoError(t, deleteThrough(t, "http://gitlab.internal/api/v4", TransportConfig{ProxyURL: proxy.URL}))
+		assert.Equal(t, "http://gitlab.internal/api/v4/projects/1/feature_flags/flag1", proxied)
+	})
+
+	t.Run("Invalid proxy URL", func(t *testing.T) {
+		_, err := NewTransport(TransportConfig{ProxyURL: "proxy:3128"})
+		assert.ErrorContains(t, err, "invalid proxy URL")
+	})
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// RemoteUserList список пользователей в том виде, в котором его возвращает GitLab.
+// В запросах к API список адресуется по IID, пользователи передаются строкой через запятую.
+type RemoteUserList struct {
+	ID        int       `json:"id"`
+	IID       int       `json:"iid"`
+	ProjectID int       `json:"project_id"`
+	Name      string    `json:"name"`
+	UserXIDs  string    `json:"user_xids"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type userListRequest struct {
+	Name     string `json:"name"`
+	UserXIDs string `json:"user_xids"`
+}
+
+// ToConfig переводит список в модель конфигурации, разбивая user_xids на отдельных пользователей
+func (l RemoteUserList) ToConfig() config.UserList {
+	list := config.UserList{Name: l.Name, UserXIDs: []string{}}
+	for _, xid := range strings.Split(l.UserXIDs, ",") {
+		if xid = strings.TrimSpace(xid); xid != "" {
+			list.UserXIDs = append(list.UserXIDs, xid)
+		}
+	}
+	return list
+}
+
+func newUserListRequest(list config.UserList) userListRequest {
+	return userListRequest{Name: list.Name, UserXIDs: strings.Join(list.UserXIDs, ",")}
+}
+
+func (c *GitLabClient) userListsURL() string {
+	return c.projectURL() + "/feature_flags_user_lists"
+}
+
+func (c *GitLabClient) userListURL(iid int) string {
+	return c.userListsURL() + "/" + strconv.Itoa(iid)
+}
+
+// GetAllUserLists возвращает все списки пользователей проекта
+func (c *GitLabClient) GetAllUserLists(ctx context.Context) ([]config.UserList, error) {
+	remote, err := c.getRemoteUserLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lists := make([]config.UserList, 0, len(remote))
+	for _, list := range remote {
+		lists = append(lists, list.ToConfig())
+	}
+	return lists, nil
+}
+
+func (c *GitLabClient) getRemoteUserLists(ctx context.Context) ([]RemoteUserList, error) {
+	var lists []RemoteUserList
+	for page := 1; page > 0; {
+		endpoint := fmt.Sprintf("%s?page=%d&per_page=%d", c.userListsURL(), page, maxPerPage)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GET request: %w", err)
+		}
+
+		resp, err := c.do(req, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user lists: %w", err)
+		}
+		pageLists, pagination, err := decodeUserLists(resp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, pageLists...)
+
+		if pagination.nextPage <= page {
+			break
+		}
+		page = pagination.nextPage
+	}
+	return lists, nil
+}
+
+func decodeUserLists(resp *http.Response) ([]RemoteUserList, Pagination, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, Pagination{}, fmt.Errorf("failed to get user lists: %w", newAPIError(resp))
+	}
+	pagination, err := getPagination(resp)
+	if err != nil {
+		return nil, Pagination{}, err
+	}
+	var lists []RemoteUserList
+	if err := json.NewDecoder(resp.Body).Decode(&lists); err != nil {
+		return nil, Pagination{}, fmt.Errorf("failed to decode user lists response: %w", err)
+	}
+	return lists, pagination, nil
+}
+
+// findUserList ищет список пользователей по имени
+func (c *GitLabClient) findUserList(ctx context.Context, name string) (RemoteUserList, bool, error) {
+	lists, err := c.getRemoteUserLists(ctx)
+	if err != nil {
+		return RemoteUserList{}, false, err
+	}
+	for _, list := range lists {
+		if list.Name == name {
+			return list, true, nil
+		}
+	}
+	return RemoteUserList{}, false, nil
+}
+
+// userListIDs сопоставляет имена списков пользователей, на которые ссылаются стратегии флага, с их ID
+func (c *GitLabClient) userListIDs(ctx context.Context, flag config.FeatureFlag) (map[string]int, error) {
+	referenced := false
+	for _, strategy := range flag.Strategies {
+		referenced = referenced || strategy.UserList != ""
+	}
+	if !referenced {
+		return nil, nil
+	}
+
+	lists, err := c.getRemoteUserLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int, len(lists))
+	for _, list := range lists {
+		ids[list.Name] = list.ID
+	}
+	for _, strategy := range flag.Strategies {
+		if _, ok := ids[strategy.UserList]; strategy.UserList != "" && !ok {
+			return nil, fmt.Errorf("user list %q referenced by feature flag %s does not exist", strategy.UserList, flag.Name)
+		}
+	}
+	return ids, nil
+}
+
+func (c *GitLabClient) CreateUserList(ctx context.Context, list config.UserList) error {
+	data, err := json.Marshal(newUserListRequest(list))
+	if err != nil {
+		return fmt.Errorf("failed to marshal user list: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.userListsURL(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create POST request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Как и для флагов, перед повтором проверяем, не создан ли список предыдущей попыткой
+	resp, err := c.do(req, func(ctx context.Context) (bool, error) {
+		_, exists, err := c.findUserList(ctx, list.Name)
+		return exists, err
+	})
+	if errors.Is(err, errAlreadyApplied) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user list %s: %w", list.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to create user list %s: %w", list.Name, newAPIError(resp))
+	}
+	return nil
+}
+
+func (c *GitLabClient) UpdateUserList(ctx context.Context, list config.UserList) error {
+	remote, exists, err := c.findUserList(ctx, list.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("failed to update user list %s: not found", list.Name)
+	}
+
+	data, err := json.Marshal(newUserListRequest(list))
+	if err != nil {
+		return fmt.Errorf("failed to marshal user list: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.userListURL(remote.IID), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create PUT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update user list %s: %w", list.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update user list %s: %w", list.Name, newAPIError(resp))
+	}
+	return nil
+}
+
+// DeleteUserList удаляет список пользователей, отсутствие списка не считается ошибкой
+func (c *GitLabClient) DeleteUserList(ctx context.Context, name string) error {
+	remote, exists, err := c.findUserList(ctx, name)
+	if err != nil || !exists {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.userListURL(remote.IID), nil)
+	if err != nil {
+		return fmt.Errorf("error creating DELETE request: %w", err)
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting user list %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return fmt.Errorf("error deleting user list %s: %w", name, newAPIError(resp))
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserLists имитирует API списков пользователей и запоминает тела запросов к флагам
+type fakeUserLists struct {
+	t       *testing.T
+	mu      sync.Mutex
+	lists   []RemoteUserList
+	flags   []map[string]interface{}
+	deleted []int
+}
+
+func (f *fakeUserLists) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/projects/1/")
+	switch {
+	case path == "feature_flags_user_lists" && r.Method == http.MethodGet:
+		require.NoError(f.t, json.NewEncoder(w).Encode(f.lists))
+	case path == "feature_flags_user_lists" && r.Method == http.MethodPost:
+		var req userListRequest
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		iid := len(f.lists) + 1
+		f.lists = append(f.lists, RemoteUserList{ID: 100 + iid, IID: iid, Name: req.Name, UserXIDs: req.UserXIDs})
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "feature_flags_user_lists/"):
+		iid, err := strconv.Atoi(strings.TrimPrefix(path, "feature_flags_user_lists/"))
+		require.NoError(f.t, err)
+		switch r.Method {
+		case http.MethodPut:
+			var req userListRequest
+			require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+			for i := range f.lists {
+				if f.lists[i].IID == iid {
+					f.lists[i].UserXIDs = req.UserXIDs
+				}
+			}
+		case http.MethodDelete:
+			f.deleted = append(f.deleted, iid)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case path == "feature_flags" && r.Method == http.MethodPost:
+		var body map[string]interface{}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&body))
+		f.flags = append(f.flags, body)
+		w.WriteHeader(http.StatusCreated)
+	case path == "feature_flags" && r.Method == http.MethodGet:
+		_, err := w.Write([]byte(`[{"name":"flag1","active":true,"strategies":[{"id":1,"name":"gitlabUserList","parameters":{},"scopes":[{"id":2,"environment_scope":"*"}],"user_list":{"id":101,"iid":1,"name":"beta","user_xids":"alice,bob"}}]}]`))
+		require.NoError(f.t, err)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func TestUserLists(t *testing.T) {
+	fake := &fakeUserLists{t: t}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	defer server.Close()
+	c := NewGitLabClient(server.URL, "token", "1", 5, WithRetryPolicy(RetryPolicy{}))
+	ctx := context.Background()
+
+	require.NoError(t, c.CreateUserList(ctx, config.UserList{Name: "beta", UserXIDs: []string{"alice", "bob"}}))
+	require.NoError(t, c.CreateUserList(ctx, config.UserList{Name: "internal", UserXIDs: []string{"carol"}}))
+	require.NoError(t, c.UpdateUserList(ctx, config.UserList{Name: "internal", UserXIDs: []string{"carol", "dave"}}))
+
+	lists, err := c.GetAllUserLists(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []config.UserList{
+		{Name: "beta", UserXIDs: []string{"alice", "bob"}},
+		{Name: "internal", UserXIDs: []string{"carol", "dave"}},
+	}, lists)
+
+	t.Run("Delete by name", func(t *testing.T) {
+		require.NoError(t, c.DeleteUserList(ctx, "internal"))
+		require.NoError(t, c.DeleteUserList(ctx, "missing"))
+		assert.Equal(t, []int{2}, fake.deleted)
+	})
+
+	t.Run("Strategies reference lists by ID", func(t *testing.T) {
+		require.NoError(t, c.CreateFeatureFlag(ctx, config.FeatureFlag{
+			Name: "flag1",
+			Strategies: []config.Strategy{
+				{Name: "gitlabUserList", UserList: "beta", Scopes: []config.Scope{{Environment: "*"}}},
+			},
+		}))
+		require.Len(t, fake.flags, 1)
+		strategy := fake.flags[0]["strategies"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, float64(101), strategy["user_list_id"])
+	})
+
+	t.Run("Unknown list", func(t *testing.T) {
+		err := c.CreateFeatureFlag(ctx, config.FeatureFlag{
+			Name:       "flag2",
+			Strategies: []config.Strategy{{Name: "gitlabUserList", UserList: "missing"}},
+		})
+		assert.ErrorContains(t, err, `user list "missing" referenced by feature flag flag2 does not exist`)
+	})
+
+	t.Run("Remote strategies carry list name", func(t *testing.T) {
+		flags, err := c.GetAllFeatureFlags(ctx)
+		require.NoError(t, err)
+		require.Len(t, flags, 1)
+		assert.Equal(t, "beta", flags[0].Strategies[0].UserList)
+	})
+}
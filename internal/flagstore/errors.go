@@ -0,0 +1,8 @@
+// Package flagstore содержит ошибки, общие для всех хранилищ флагов: GitLab, памяти и файла.
+// Сервис проверяет их через errors.Is, не зная, с каким хранилищем работает.
+package flagstore
+
+import "errors"
+
+// ErrNotFound флаг или список пользователей с таким именем отсутствует
+var ErrNotFound = errors.New("not found")
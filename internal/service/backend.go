@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/client"
+)
+
+// FeatureFlagBackend хранилище флагов, с которым синхронизируется конфигурация.
+// Основная реализация *client.GitLabClient, для тестов и репетиций без GitLab
+// есть реализации в пакете backend.
+type FeatureFlagBackend interface {
+	GetAllFeatureFlags(ctx context.Context) ([]config.FeatureFlag, error)
+	GetFeatureFlag(ctx context.Context, flagName string) (config.FeatureFlag, error)
+	CreateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error
+	UpdateFeatureFlag(ctx context.Context, flag config.FeatureFlag) error
+	// DeleteFeatureFlag не считает ошибкой отсутствие флага
+	DeleteFeatureFlag(ctx context.Context, flagName string) error
+
+	GetAllUserLists(ctx context.Context) ([]config.UserList, error)
+	CreateUserList(ctx context.Context, list config.UserList) error
+	UpdateUserList(ctx context.Context, list config.UserList) error
+	// DeleteUserList не считает ошибкой отсутствие списка
+	DeleteUserList(ctx context.Context, name string) error
+}
+
+var _ FeatureFlagBackend = (*client.GitLabClient)(nil)
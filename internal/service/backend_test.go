@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ FeatureFlagBackend = (*backend.Memory)(nil)
+	_ FeatureFlagBackend = (*backend.File)(nil)
+)
+
+func TestSyncWithMemoryBackend(t *testing.T) {
+	ctx := context.Background()
+	memory := backend.NewMemory(
+		config.FeatureFlag{Name: "changed", Description: markManaged("old"), Strategies: []config.Strategy{}},
+		config.FeatureFlag{Name: "orphaned", Description: markManaged(""), Strategies: []config.Strategy{}},
+		config.FeatureFlag{Name: "manual", Description: "created by hand", Strategies: []config.Strategy{}},
+	)
+	desired := []config.FeatureFlag{
+		{Name: "changed", Description: "new", Active: true},
+		{Name: "created", Description: "created", Strategies: []config.Strategy{
+			{Name: "default", Parameters: map[string]interface{}{}, Scopes: []config.Scope{{Environment: "*"}}},
+		}},
+	}
+
+	ffs := FeatureFlagService{Backend: memory, Prune: true}
+	require.NoError(t, ffs.SyncFeatureFlags(desired))
+
+	flags, err := memory.GetAllFeatureFlags(ctx)
+	require.NoError(t, err)
+	names := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		names = append(names, flag.Name)
+	}
+	assert.Equal(t, []string{"changed", "created", "manual"}, names)
+
+	changed, err := memory.GetFeatureFlag(ctx, "changed")
+	require.NoError(t, err)
+	assert.Equal(t, markManaged("new"), changed.Description)
+	assert.True(t, changed.Active)
+
+	manual, err := memory.GetFeatureFlag(ctx, "manual")
+	require.NoError(t, err)
+	assert.Equal(t, "created by hand", manual.Description)
+
+	t.Run("Second sync has nothing to do", func(t *testing.T) {
+		plan, err := ffs.PlanFeatureFlags(ctx, desired)
+		require.NoError(t, err)
+		assert.True(t, plan.Empty(), "unexpected plan: %+v", plan)
+	})
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// DriftReport отличия флагов GitLab от конфигурации
+type DriftReport struct {
+	Fingerprint string `json:"fingerprint"`
+	// Changed управляемые флаги, значения которых в GitLab отличаются от конфигурации
+	Changed []FlagUpdate `json:"changed"`
+	// Missing флаги из конфигурации, которых нет в GitLab
+	Missing []string `json:"missing"`
+	// Unexpected управляемые флаги GitLab, которых нет в конфигурации
+	Unexpected []string `json:"unexpected"`
+
+	// Списки пользователей: изменённые, отсутствующие в GitLab и лишние (только с Prune)
+	ChangedUserLists    []UserListUpdate `json:"changed_user_lists,omitempty"`
+	MissingUserLists    []string         `json:"missing_user_lists,omitempty"`
+	UnexpectedUserLists []string         `json:"unexpected_user_lists,omitempty"`
+}
+
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Changed) > 0 || len(r.Missing) > 0 || len(r.Unexpected) > 0 ||
+		len(r.ChangedUserLists) > 0 || len(r.MissingUserLists) > 0 || len(r.UnexpectedUserLists) > 0
+}
+
+// DetectDrift сравнивает флаги GitLab с конфигурацией, ничего не изменяя, и печатает отличия в Out
+func (ffs *FeatureFlagService) DetectDrift(flags []config.FeatureFlag) (*DriftReport, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	plan, err := ffs.PlanFeatureFlags(ctx, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{
+		Fingerprint: plan.Fingerprint,
+		Changed:     append([]FlagUpdate{}, plan.Update...),
+		Missing:     make([]string, 0, len(plan.Create)),
+		Unexpected:  append(append([]string{}, plan.Delete...), plan.Orphaned...),
+	}
+	for _, flag := range plan.Create {
+		report.Missing = append(report.Missing, flag.Name)
+	}
+	sort.Slice(report.Changed, func(i, j int) bool { return report.Changed[i].Flag.Name < report.Changed[j].Flag.Name })
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unexpected)
+
+	if plan.userListsLen() > 0 {
+		report.ChangedUserLists = append([]UserListUpdate{}, plan.UpdateUserLists...)
+		report.UnexpectedUserLists = append([]string{}, plan.DeleteUserLists...)
+		for _, list := range plan.CreateUserLists {
+			report.MissingUserLists = append(report.MissingUserLists, list.Name)
+		}
+		sort.Slice(report.ChangedUserLists, func(i, j int) bool {
+			return report.ChangedUserLists[i].List.Name < report.ChangedUserLists[j].List.Name
+		})
+		sort.Strings(report.MissingUserLists)
+		sort.Strings(report.UnexpectedUserLists)
+		log.Printf("Drifted user lists: %d changed, %d missing, %d unexpected",
+			len(report.ChangedUserLists), len(report.MissingUserLists), len(report.UnexpectedUserLists))
+	}
+
+	log.Printf("Drifted flags: %d changed, %d missing, %d unexpected", len(report.Changed), len(report.Missing), len(report.Unexpected))
+	if err := report.WriteText(ffs.out()); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *DriftReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	if !r.HasDrift() {
+		b.WriteString("No drift. Remote feature flags match the configuration.\n")
+	}
+	for _, u := range r.ChangedUserLists {
+		fmt.Fprintf(&b, "~ user list %s\n", u.List.Name)
+		for _, change := range u.Changes {
+			fmt.Fprintf(&b, "    %s: remote %s, desired %s\n", change.Field, change.Remote, change.Desired)
+		}
+	}
+	for _, name := range r.MissingUserLists {
+		fmt.Fprintf(&b, "+ user list %s (missing in GitLab)\n", name)
+	}
+	for _, name := range r.UnexpectedUserLists {
+		fmt.Fprintf(&b, "- user list %s (not in config)\n", name)
+	}
+	for _, u := range r.Changed {
+		fmt.Fprintf(&b, "~ %s\n", u.Flag.Name)
+		for _, change := range u.Changes {
+			fmt.Fprintf(&b, "    %s: remote %s, desired %s\n", change.Field, change.Remote, change.Desired)
+		}
+	}
+	for _, name := range r.Missing {
+		fmt.Fprintf(&b, "+ %s (missing in GitLab)\n", name)
+	}
+	for _, name := range r.Unexpected {
+		fmt.Fprintf(&b, "- %s (not in config)\n", name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDriftReport сохраняет отчёт о дрейфе в JSON файл
+func WriteDriftReport(fileName string, report *DriftReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling drift report: %w", err)
+	}
+	if err := os.WriteFile(fileName, data, 0o644); err != nil {
+		return fmt.Errorf("error writing drift report: %w", err)
+	}
+	return nil
+}
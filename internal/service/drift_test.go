@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectDrift(t *testing.T) {
+	desired := []config.FeatureFlag{
+		{Name: "toggled", Description: "toggled by hand", Active: true},
+		{Name: "same", Description: "in sync", Active: true},
+		{Name: "missing", Description: "never created", Active: true},
+	}
+
+	t.Run("drift", func(t *testing.T) {
+		gitlab := newFakeGitLab(t,
+			config.FeatureFlag{Name: "toggled", Description: "toggled by hand " + config.ManagedMarker, Active: false},
+			config.FeatureFlag{Name: "same", Description: "in sync " + config.ManagedMarker, Active: true},
+			config.FeatureFlag{Name: "leftover", Description: "removed from config " + config.ManagedMarker, Active: true},
+			config.FeatureFlag{Name: "manual", Description: "created in UI", Active: true},
+		)
+
+		var out bytes.Buffer
+		ffs := FeatureFlagService{Backend: gitlab.client(), Out: &out}
+		report, err := ffs.DetectDrift(desired)
+		require.NoError(t, err)
+
+		assert.True(t, report.HasDrift())
+		require.Len(t, report.Changed, 1)
+		assert.Equal(t, "toggled", report.Changed[0].Flag.Name)
+		assert.Equal(t, []string{"missing"}, report.Missing)
+		assert.Equal(t, []string{"leftover"}, report.Unexpected)
+		assert.Equal(t, `~ toggled
+    active: remote false, desired true
++ missing (missing in GitLab)
+- leftover (not in config)
+`, out.String())
+		assert.Len(t, gitlab.snapshot(), 4, "drift detection must not modify GitLab")
+	})
+
+	t.Run("no_drift", func(t *testing.T) {
+		gitlab := newFakeGitLab(t, config.FeatureFlag{Name: "same", Description: "in sync " + config.ManagedMarker, Active: true})
+
+		var out bytes.Buffer
+		ffs := FeatureFlagService{Backend: gitlab.client(), Out: &out}
+		report, err := ffs.DetectDrift(desired[1:2])
+		require.NoError(t, err)
+
+		assert.False(t, report.HasDrift())
+		assert.Equal(t, "No drift. Remote feature flags match the configuration.\n", out.String())
+	})
+	t.Run("user_lists", func(t *testing.T) {
+		memory := backend.NewMemory().AddUserLists(config.UserList{Name: "beta", UserXIDs: []string{"alice"}})
+
+		var out bytes.Buffer
+		ffs := FeatureFlagService{Backend: memory, Out: &out, UserLists: []config.UserList{
+			{Name: "beta", UserXIDs: []string{"alice", "bob"}},
+			{Name: "gamma", UserXIDs: []string{"carol"}},
+		}}
+		report, err := ffs.DetectDrift(nil)
+		require.NoError(t, err)
+
+		assert.True(t, report.HasDrift())
+		require.Len(t, report.ChangedUserLists, 1)
+		assert.Equal(t, "beta", report.ChangedUserLists[0].List.Name)
+		assert.Equal(t, []string{"gamma"}, report.MissingUserLists)
+		assert.Equal(t, `~ user list beta
+    user_xids: remote alice, desired alice, bob
++ user list gamma (missing in GitLab)
+`, out.String())
+	})
+}
@@ -2,181 +2,312 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"reflect"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nkrus/gitlab-flagman/config"
-	"github.com/nkrus/gitlab-flagman/internal/client"
 )
 
 const maxConcurrency = 5
 
 type FeatureFlagService struct {
-	GitLabClient *client.GitLabClient
+	// Backend хранилище флагов, обычно *client.GitLabClient
+	Backend FeatureFlagBackend
+	// DryRun только строит план изменений и печатает его в Out, не изменяя GitLab
+	DryRun bool
+	// Out куда печатается отчёт о плане, по умолчанию os.Stdout
+	Out io.Writer
+	// PlanOut путь, по которому в режиме DryRun сохраняется план для команды apply
+	PlanOut string
+	// Prune удаляет из GitLab флаги, отсутствующие в конфигурации
+	Prune bool
+	// PruneUserLists удаляет из GitLab неиспользуемые списки пользователей, отсутствующие в разделе userLists.
+	// У списков нет метки управления, поэтому их удаление включается отдельно от Prune.
+	PruneUserLists bool
+	// MaxDeletes ограничивает число удалений за синхронизацию, nil - без ограничений
+	MaxDeletes *DeletionLimit
+	// Force разрешает удаление сверх MaxDeletes
+	Force bool
+	// ContinueOnError продолжает обработку остальных флагов после ошибки
+	ContinueOnError bool
+	// Selection ограничивает синхронизацию частью флагов
+	Selection Selection
+	// RollbackOnFailure при любой ошибке возвращает затронутые флаги к состоянию до синхронизации
+	RollbackOnFailure bool
+	// UserLists списки пользователей из конфигурации. nil - списки не управляются,
+	// стратегии могут ссылаться только на уже существующие в GitLab
+	UserLists []config.UserList
 }
 
 func (ffs *FeatureFlagService) SyncFeatureFlags(flags []config.FeatureFlag) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	plan, err := ffs.PlanFeatureFlags(ctx, flags)
+	if err != nil {
+		return err
+	}
+
+	if ffs.DryRun {
+		log.Println("Dry run: no changes will be applied")
+		if err := plan.WriteReport(ffs.out()); err != nil {
+			return err
+		}
+		if ffs.PlanOut != "" {
+			if err := WritePlanFile(ffs.PlanOut, plan); err != nil {
+				return err
+			}
+			log.Printf("Plan saved to %s", ffs.PlanOut)
+		}
+		return ffs.checkDeletionLimit(plan)
+	}
+
+	_, err = ffs.ApplyPlan(ctx, plan)
+	return err
+}
+
+// PlanFeatureFlags сравнивает флаги из конфигурации с флагами в GitLab и
+// возвращает план изменений, ничего не меняя удалённо
+func (ffs *FeatureFlagService) PlanFeatureFlags(ctx context.Context, flags []config.FeatureFlag) (*Plan, error) {
 	log.Printf("Total flags in config: %d", len(flags))
+	if !ffs.Selection.Empty() {
+		flags = ffs.Selection.filter(flags)
+		log.Printf("Flags selected for sync: %d", len(flags))
+	}
 
-	existingFlags, err := ffs.GitLabClient.GetAllFeatureFlags(ctx)
+	desiredFlagMap := make(map[string]config.FeatureFlag)
+	for _, df := range flags {
+		if _, exists := desiredFlagMap[df.Name]; exists {
+			return nil, fmt.Errorf("feature flag %s is defined more than once", df.Name)
+		}
+		desiredFlagMap[df.Name] = df
+	}
+
+	existingFlags, err := ffs.Backend.GetAllFeatureFlags(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve existing feature flags: %w", err)
+		return nil, fmt.Errorf("failed to retrieve existing feature flags: %w", err)
 	}
 
 	log.Printf("Total flags found remotely: %d", len(existingFlags))
 	remoteFlagMap := make(map[string]config.FeatureFlag)
+	managed := make(map[string]bool)
 	for _, ef := range existingFlags {
+		managed[ef.Name] = isManaged(ef.Description)
+		ef.Description = unmarkManaged(ef.Description)
 		remoteFlagMap[ef.Name] = ef
 	}
 
-	desiredFlagMap := make(map[string]config.FeatureFlag)
-	for _, df := range flags {
-		desiredFlagMap[df.Name] = df
+	remoteLists, err := ffs.Backend.GetAllUserLists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve existing user lists: %w", err)
 	}
 
-	var flagsToAdd []config.FeatureFlag
-	var flagsToUpdate []config.FeatureFlag
-	var flagsToDelete []string
+	remoteFingerprint, err := fingerprint(existingFlags, remoteLists)
+	if err != nil {
+		return nil, err
+	}
 
+	plan := &Plan{Fingerprint: remoteFingerprint}
 	for _, flag := range flags {
 		if remoteFlag, exists := remoteFlagMap[flag.Name]; exists {
+			if !managed[flag.Name] {
+				log.Printf("Flag %s exists in GitLab but is not managed by gitlab-flagman, skipping (run adopt to take it over)", flag.Name)
+				plan.Unmanaged = append(plan.Unmanaged, flag.Name)
+				continue
+			}
 			if !flagsEqual(remoteFlag, flag) {
-				flagsToUpdate = append(flagsToUpdate, flag)
+				plan.Update = append(plan.Update, FlagUpdate{Flag: flag, Changes: diffFlags(remoteFlag, flag)})
 			}
 		} else {
-			flagsToAdd = append(flagsToAdd, flag)
+			plan.Create = append(plan.Create, flag)
 		}
 	}
 
+	var orphaned []string
 	for _, existingFlag := range existingFlags {
+		if !managed[existingFlag.Name] || !ffs.Selection.includesRemote(existingFlag.Name, desiredFlagMap) {
+			continue
+		}
+		plan.RemoteCount++
 		if _, exists := desiredFlagMap[existingFlag.Name]; !exists {
-			flagsToDelete = append(flagsToDelete, existingFlag.Name)
+			orphaned = append(orphaned, existingFlag.Name)
 		}
 	}
-
-	log.Printf("Flags to delete: %d", len(flagsToDelete))
-	log.Printf("Flags to add: %d", len(flagsToAdd))
-	log.Printf("Flags to update: %d", len(flagsToUpdate))
-
-	log.Println("Synchronization process started")
-
-	if err := processFlagsConcurrently(ctx, flagsToDelete, ffs.deleteFlag, maxConcurrency); err != nil {
-		return fmt.Errorf("failed to delete feature flags: %w", err)
+	if ffs.Prune {
+		plan.Delete = orphaned
+	} else if len(orphaned) > 0 {
+		plan.Orphaned = orphaned
+		log.Printf("Flags not in config: %d (kept, pass -prune to delete them)", len(orphaned))
 	}
 
-	if err := processFlagsConcurrently(ctx, flagsToAdd, ffs.addFlag, maxConcurrency); err != nil {
-		return fmt.Errorf("failed to add feature flags: %w", err)
+	if err := ffs.planUserLists(plan, flags, existingFlags, remoteLists); err != nil {
+		return nil, err
 	}
 
-	if err := processFlagsConcurrently(ctx, flagsToUpdate, ffs.updateFlag, maxConcurrency); err != nil {
-		return fmt.Errorf("failed to update feature flags: %w", err)
-	}
-	log.Printf("Synced %d flags successfully", len(flagsToDelete)+len(flagsToAdd)+len(flagsToUpdate))
+	log.Printf("Flags to delete: %d", len(plan.Delete))
+	log.Printf("Flags to add: %d", len(plan.Create))
+	log.Printf("Flags to update: %d", len(plan.Update))
 
-	return nil
+	return plan, nil
 }
 
-func flagsEqual(a, b config.FeatureFlag) bool {
-	if a.Name != b.Name || a.Description != b.Description || a.Active != b.Active {
-		return false
+// ApplyPlan применяет план изменений к GitLab и возвращает результат по каждому флагу.
+// Без ContinueOnError обработка останавливается на первой ошибке, оставшиеся флаги пропускаются.
+// С RollbackOnFailure к результатам добавляются результаты отката затронутых флагов.
+func (ffs *FeatureFlagService) ApplyPlan(ctx context.Context, plan *Plan) ([]Result, error) {
+	if err := ffs.checkDeletionLimit(plan); err != nil {
+		return nil, err
 	}
 
-	if len(a.Strategies) != len(b.Strategies) {
-		return false
-	}
-
-	for i := range a.Strategies {
-		if a.Strategies[i].Name != b.Strategies[i].Name {
-			return false
+	var snapshot map[string]config.FeatureFlag
+	if ffs.RollbackOnFailure {
+		var err error
+		if snapshot, err = ffs.snapshotFeatureFlags(ctx); err != nil {
+			return nil, err
 		}
+	}
 
-		if !mapsEqual(a.Strategies[i].Parameters, b.Strategies[i].Parameters) {
-			return false
-		}
+	log.Println("Synchronization process started")
 
-		if len(a.Strategies[i].Scopes) != len(b.Strategies[i].Scopes) {
-			return false
-		}
-		aScopes := make(map[string]bool, len(a.Strategies[i].Scopes))
-		for _, scope := range a.Strategies[i].Scopes {
-			aScopes[scope.Environment] = true
-		}
-		for _, scope := range b.Strategies[i].Scopes {
-			if !aScopes[scope.Environment] {
-				return false
+	stop := ffs.stopOnError()
+	var results []Result
+	results = append(results, processFlagsConcurrently(ctx, ActionCreateUserList, plan.CreateUserLists, userListName, ffs.Backend.CreateUserList, maxConcurrency, stop)...)
+	results = append(results, processFlagsConcurrently(ctx, ActionUpdateUserList, plan.UpdateUserLists, userListUpdateName, ffs.updateUserList, maxConcurrency, stop)...)
+	results = append(results, processFlagsConcurrently(ctx, ActionDelete, plan.Delete, flagName, ffs.deleteFlag, maxConcurrency, stop)...)
+	results = append(results, processFlagsConcurrently(ctx, ActionCreate, plan.Create, configFlagName, ffs.addFlag, maxConcurrency, stop)...)
+	results = append(results, processFlagsConcurrently(ctx, ActionUpdate, plan.Update, updateFlagName, ffs.updateFlag, maxConcurrency, stop)...)
+	results = append(results, processFlagsConcurrently(ctx, ActionDeleteUserList, plan.DeleteUserLists, flagName, ffs.Backend.DeleteUserList, maxConcurrency, stop)...)
+
+	if err := collectFailures(results); err != nil {
+		err = fmt.Errorf("failed to sync feature flags: %w", err)
+		if snapshot != nil {
+			restored, rollbackErr := ffs.rollback(ctx, snapshot, results)
+			if rollbackErr != nil {
+				return append(results, restored...), errors.Join(err, rollbackErr)
 			}
+			return append(results, restored...), fmt.Errorf("%w\nall touched flags were rolled back", err)
 		}
+		return results, err
 	}
+	log.Printf("Synced %d flags and user lists successfully", plan.Len())
 
-	return true
+	return results, nil
 }
 
-func mapsEqual(a, b map[string]interface{}) bool {
-	if len(a) != len(b) {
-		return false
-	}
+// stopper общий для всех фаз признак остановки после ошибки
+type stopper struct {
+	stopped         atomic.Bool
+	continueOnError bool
+}
 
-	for key, valueA := range a {
-		valueB, exists := b[key]
-		if !exists {
-			return false
-		}
-		if !reflect.DeepEqual(valueA, valueB) {
-			return false
+// stopOnError возвращает stopper, который останавливает обработку на первой ошибке,
+// а с ContinueOnError - только на ошибках, после которых остальные запросы тоже не пройдут
+func (ffs *FeatureFlagService) stopOnError() *stopper {
+	return &stopper{continueOnError: ffs.ContinueOnError}
+}
+
+func (s *stopper) fail(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	if reason := fatalError(err); reason != "" {
+		if !s.stopped.Swap(true) {
+			log.Printf("Stopping sync, %s: %v", reason, err)
 		}
+		return
+	}
+	if !s.continueOnError {
+		s.stopped.Store(true)
 	}
+}
 
-	return true
+func (s *stopper) Stopped() bool {
+	return s != nil && s.stopped.Load()
 }
 
+func (ffs *FeatureFlagService) out() io.Writer {
+	if ffs.Out == nil {
+		return os.Stdout
+	}
+	return ffs.Out
+}
+
+// processFlagsConcurrently выполняет action для каждого элемента и возвращает результат по каждому флагу.
+// Если stop не nil и решает остановиться после ошибки, новые элементы не запускаются,
+// а помечаются как пропущенные. Уже выполняющиеся запросы завершаются.
 func processFlagsConcurrently[T any](
 	ctx context.Context,
+	kind Action,
 	items []T,
+	name func(T) string,
 	action func(context.Context, T) error,
 	concurrency int,
-) error {
+	stop *stopper,
+) []Result {
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(items))
+	results := make([]Result, len(items))
 	sem := make(chan struct{}, concurrency)
 
-	for _, item := range items {
-		wg.Add(1)
+	for i, item := range items {
+		results[i] = Result{Action: kind, Flag: name(item), Skipped: true}
+
 		sem <- struct{}{}
-		go func(item T) {
+		if stop.Stopped() {
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			if err := action(ctx, item); err != nil {
-				errChan <- err
-			}
-		}(item)
+			start := time.Now()
+			err := action(ctx, item)
+			results[i] = newResult(kind, results[i].Flag, err, time.Since(start))
+			stop.fail(err)
+		}(i, item)
 	}
 
 	wg.Wait()
-	close(errChan)
+	return results
+}
 
-	if len(errChan) > 0 {
-		return <-errChan
-	}
-	return nil
+func flagName(name string) string {
+	return name
+}
+
+func configFlagName(flag config.FeatureFlag) string {
+	return flag.Name
+}
+
+func updateFlagName(update FlagUpdate) string {
+	return update.Flag.Name
 }
 
 func (ffs *FeatureFlagService) addFlag(ctx context.Context, flag config.FeatureFlag) error {
-	return ffs.GitLabClient.CreateFeatureFlag(ctx, flag)
+	flag.Description = markManaged(flag.Description)
+	return ffs.Backend.CreateFeatureFlag(ctx, flag)
 }
 
 func (ffs *FeatureFlagService) deleteFlag(ctx context.Context, flagName string) error {
-	return ffs.GitLabClient.DeleteFeatureFlag(ctx, flagName)
+	return ffs.Backend.DeleteFeatureFlag(ctx, flagName)
 }
 
-func (ffs *FeatureFlagService) updateFlag(ctx context.Context, flag config.FeatureFlag) error {
-	if err := ffs.GitLabClient.DeleteFeatureFlag(ctx, flag.Name); err != nil {
-		return err
+func (ffs *FeatureFlagService) updateFlag(ctx context.Context, update FlagUpdate) error {
+	flag := update.Flag
+	flag.Description = markManaged(flag.Description)
+	err := ffs.Backend.UpdateFeatureFlag(ctx, flag)
+	if errors.Is(err, ErrNotFound) {
+		// Флаг удалили в GitLab после построения плана, создаём его заново
+		log.Printf("Flag %s disappeared from GitLab during sync, creating it again", flag.Name)
+		return ffs.Backend.CreateFeatureFlag(ctx, flag)
 	}
-	return ffs.GitLabClient.CreateFeatureFlag(ctx, flag)
+	return err
 }
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/client"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitLab минимальная реализация API фича-флагов GitLab для тестов сервиса
+type fakeGitLab struct {
+	t      *testing.T
+	mu     sync.Mutex
+	flags  map[string]config.FeatureFlag
+	server *httptest.Server
+	// fail возвращает HTTP статус, которым нужно ответить вместо обработки запроса, или 0
+	fail func(r *http.Request) int
+}
+
+func newFakeGitLab(t *testing.T, flags ...config.FeatureFlag) *fakeGitLab {
+	t.Helper()
+	f := &fakeGitLab{t: t, flags: make(map[string]config.FeatureFlag)}
+	for _, flag := range flags {
+		f.flags[flag.Name] = flag
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeGitLab) client() *client.GitLabClient {
+	return client.NewGitLabClient(f.server.URL, "token", "1", 10, client.WithRetryPolicy(client.RetryPolicy{}))
+}
+
+func (f *fakeGitLab) set(flag config.FeatureFlag) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[flag.Name] = flag
+}
+
+func (f *fakeGitLab) snapshot() map[string]config.FeatureFlag {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make(map[string]config.FeatureFlag, len(f.flags))
+	for name, flag := range f.flags {
+		result[name] = flag
+	}
+	return result
+}
+
+func (f *fakeGitLab) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail != nil {
+		if status := f.fail(r); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	if r.URL.Path == "/projects/1/feature_flags_user_lists" && r.Method == http.MethodGet {
+		w.Header().Set("X-Total-Pages", "1")
+		_, _ = w.Write([]byte("[]"))
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/projects/1/feature_flags"), "/")
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		list := make([]config.FeatureFlag, 0, len(f.flags))
+		for _, flag := range f.flags {
+			list = append(list, flag)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		w.Header().Set("X-Total-Pages", "1")
+		require.NoError(f.t, json.NewEncoder(w).Encode(list))
+	case r.Method == http.MethodGet:
+		flag, ok := f.flags[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.NoError(f.t, json.NewEncoder(w).Encode(flag))
+	case r.Method == http.MethodPost:
+		var flag config.FeatureFlag
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&flag))
+		if _, exists := f.flags[flag.Name]; exists {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.flags[flag.Name] = flag
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		if _, exists := f.flags[name]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.flags[name] = decodeUpdate(f.t, r)
+	case r.Method == http.MethodDelete:
+		if _, exists := f.flags[name]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.flags, name)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+// decodeUpdate применяет тело PUT запроса, отбрасывая стратегии и окружения с _destroy
+func decodeUpdate(t *testing.T, r *http.Request) config.FeatureFlag {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Active      bool   `json:"active"`
+		Strategies  []struct {
+			Name       string                 `json:"name"`
+			Parameters map[string]interface{} `json:"parameters"`
+			Destroy    bool                   `json:"_destroy"`
+			Scopes     []struct {
+				Environment string `json:"environment_scope"`
+				Destroy     bool   `json:"_destroy"`
+			} `json:"scopes"`
+		} `json:"strategies"`
+	}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+	flag := config.FeatureFlag{Name: body.Name, Description: body.Description, Active: body.Active}
+	for _, s := range body.Strategies {
+		if s.Destroy {
+			continue
+		}
+		strategy := config.Strategy{Name: s.Name, Parameters: s.Parameters}
+		for _, scope := range s.Scopes {
+			if !scope.Destroy {
+				strategy.Scopes = append(strategy.Scopes, config.Scope{Environment: scope.Environment})
+			}
+		}
+		flag.Strategies = append(flag.Strategies, strategy)
+	}
+	return flag
+}
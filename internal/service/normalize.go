@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// normalizedStrategy каноническая форма стратегии: параметры приведены к строкам,
+// окружения отсортированы и не повторяются
+type normalizedStrategy struct {
+	Name       string
+	Parameters map[string]string
+	Scopes     []string
+	UserList   string
+}
+
+// normalizedFlag каноническая форма флага для семантического сравнения.
+// Стратегии отсортированы, теги не учитываются, так как GitLab их не хранит.
+type normalizedFlag struct {
+	Description string
+	Active      bool
+	Strategies  []normalizedStrategy
+}
+
+func normalizeFlag(flag config.FeatureFlag) normalizedFlag {
+	normalized := normalizedFlag{
+		Description: strings.TrimSpace(flag.Description),
+		Active:      flag.Active,
+		Strategies:  make([]normalizedStrategy, 0, len(flag.Strategies)),
+	}
+	for _, strategy := range flag.Strategies {
+		normalized.Strategies = append(normalized.Strategies, normalizeStrategy(strategy))
+	}
+	sort.SliceStable(normalized.Strategies, func(i, j int) bool {
+		return normalized.Strategies[i].key() < normalized.Strategies[j].key()
+	})
+	return normalized
+}
+
+func normalizeStrategy(strategy config.Strategy) normalizedStrategy {
+	normalized := normalizedStrategy{
+		Name:       strings.TrimSpace(strategy.Name),
+		Parameters: config.NormalizeParameters(strategy.Parameters),
+		Scopes:     make([]string, 0, len(strategy.Scopes)),
+		UserList:   strings.TrimSpace(strategy.UserList),
+	}
+	for _, scope := range strategy.Scopes {
+		normalized.Scopes = append(normalized.Scopes, strings.TrimSpace(scope.Environment))
+	}
+	sort.Strings(normalized.Scopes)
+	normalized.Scopes = slices.Compact(normalized.Scopes)
+	return normalized
+}
+
+// key однозначно описывает стратегию и задаёт порядок сортировки
+func (s normalizedStrategy) key() string {
+	return s.Name + "\x00" + s.parametersString() + "\x00" + strings.Join(s.Scopes, ",") + "\x00" + s.UserList
+}
+
+func (s normalizedStrategy) equal(other normalizedStrategy) bool {
+	return s.key() == other.key()
+}
+
+func (s normalizedStrategy) parametersString() string {
+	keys := make([]string, 0, len(s.Parameters))
+	for key := range s.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, key+"="+strconv.Quote(s.Parameters[key]))
+	}
+	return strings.Join(params, ", ")
+}
+
+func (s normalizedStrategy) String() string {
+	str := fmt.Sprintf("%s(%s) [%s]", s.Name, s.parametersString(), strings.Join(s.Scopes, ", "))
+	if s.UserList != "" {
+		str += " user_list=" + strconv.Quote(s.UserList)
+	}
+	return str
+}
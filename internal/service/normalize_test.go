@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagsEqual(t *testing.T) {
+	strategy := func(name string, params map[string]interface{}, envs ...string) config.Strategy {
+		s := config.Strategy{Name: name, Parameters: params}
+		for _, env := range envs {
+			s.Scopes = append(s.Scopes, config.Scope{Environment: env})
+		}
+		return s
+	}
+	flag := func(strategies ...config.Strategy) config.FeatureFlag {
+		return config.FeatureFlag{Name: "flag", Description: "desc", Active: true, Strategies: strategies}
+	}
+
+	testCases := []struct {
+		name     string
+		a, b     config.FeatureFlag
+		expected bool
+	}{
+		{
+			name:     "identical",
+			a:        flag(strategy("default", nil, "PROD")),
+			b:        flag(strategy("default", nil, "PROD")),
+			expected: true,
+		},
+		{
+			name:     "strategies in different order",
+			a:        flag(strategy("default", nil, "PROD"), strategy("userWithId", map[string]interface{}{"userIds": "1"}, "TEST")),
+			b:        flag(strategy("userWithId", map[string]interface{}{"userIds": "1"}, "TEST"), strategy("default", nil, "PROD")),
+			expected: true,
+		},
+		{
+			name:     "scopes in different order",
+			a:        flag(strategy("default", nil, "PROD", "TEST")),
+			b:        flag(strategy("default", nil, "TEST", "PROD")),
+			expected: true,
+		},
+		{
+			name:     "duplicated scope",
+			a:        flag(strategy("default", nil, "PROD", "PROD")),
+			b:        flag(strategy("default", nil, "PROD")),
+			expected: true,
+		},
+		{
+			name:     "duplicated scope hides a missing one",
+			a:        flag(strategy("default", nil, "PROD", "PROD")),
+			b:        flag(strategy("default", nil, "PROD", "TEST")),
+			expected: false,
+		},
+		{
+			name:     "extra scope on either side",
+			a:        flag(strategy("default", nil, "PROD", "TEST")),
+			b:        flag(strategy("default", nil, "PROD")),
+			expected: false,
+		},
+		{
+			name:     "nil and empty parameters",
+			a:        flag(strategy("default", nil, "PROD")),
+			b:        flag(strategy("default", map[string]interface{}{}, "PROD")),
+			expected: true,
+		},
+		{
+			name:     "yaml integer and gitlab string",
+			a:        flag(strategy("gradualRolloutUserId", map[string]interface{}{"percentage": 50, "groupId": "default"}, "*")),
+			b:        flag(strategy("gradualRolloutUserId", map[string]interface{}{"percentage": "50", "groupId": "default"}, "*")),
+			expected: true,
+		},
+		{
+			name:     "user ids formatting",
+			a:        flag(strategy("userWithId", map[string]interface{}{"userIds": "1, 2, 3"}, "*")),
+			b:        flag(strategy("userWithId", map[string]interface{}{"userIds": "3,2,1"}, "*")),
+			expected: true,
+		},
+		{
+			name:     "different user ids",
+			a:        flag(strategy("userWithId", map[string]interface{}{"userIds": "1,2"}, "*")),
+			b:        flag(strategy("userWithId", map[string]interface{}{"userIds": "1,2,3"}, "*")),
+			expected: false,
+		},
+		{
+			name:     "different strategy count",
+			a:        flag(strategy("default", nil, "PROD")),
+			b:        flag(strategy("default", nil, "PROD"), strategy("default", nil, "TEST")),
+			expected: false,
+		},
+		{
+			name:     "tags are ignored",
+			a:        config.FeatureFlag{Name: "flag", Tags: []string{"payments"}},
+			b:        config.FeatureFlag{Name: "flag"},
+			expected: true,
+		},
+		{
+			name:     "different active",
+			a:        config.FeatureFlag{Name: "flag", Active: true},
+			b:        config.FeatureFlag{Name: "flag", Active: false},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, flagsEqual(tc.a, tc.b))
+			assert.Equal(t, tc.expected, flagsEqual(tc.b, tc.a), "comparison must be symmetric")
+		})
+	}
+}
+
+func TestDiffFlagsPairsStrategiesByName(t *testing.T) {
+	remote := config.FeatureFlag{Strategies: []config.Strategy{
+		{Name: "default", Scopes: []config.Scope{{Environment: "PROD"}}},
+		{Name: "userWithId", Parameters: map[string]interface{}{"userIds": "1,2"}, Scopes: []config.Scope{{Environment: "*"}}},
+	}}
+	desired := config.FeatureFlag{Strategies: []config.Strategy{
+		{Name: "userWithId", Parameters: map[string]interface{}{"userIds": "2, 1, 3"}, Scopes: []config.Scope{{Environment: "*"}}},
+		{Name: "default", Scopes: []config.Scope{{Environment: "PROD"}}},
+	}}
+
+	assert.Equal(t, []FieldChange{
+		{Field: "strategies[userWithId].parameters.userIds", Remote: `"1,2"`, Desired: `"1,2,3"`},
+	}, diffFlags(remote, desired))
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+func isManaged(description string) bool {
+	return strings.HasSuffix(strings.TrimSpace(description), config.ManagedMarker)
+}
+
+func markManaged(description string) string {
+	if isManaged(description) {
+		return description
+	}
+	if description == "" {
+		return config.ManagedMarker
+	}
+	return description + " " + config.ManagedMarker
+}
+
+func unmarkManaged(description string) string {
+	trimmed := strings.TrimSpace(description)
+	if !strings.HasSuffix(trimmed, config.ManagedMarker) {
+		return description
+	}
+	return strings.TrimSpace(strings.TrimSuffix(trimmed, config.ManagedMarker))
+}
+
+// AdoptFeatureFlags помечает существующие флаги GitLab как управляемые gitlab-flagman.
+// Если names пуст, берутся все неуправляемые флаги, объявленные в flags.
+func (ffs *FeatureFlagService) AdoptFeatureFlags(names []string, flags []config.FeatureFlag) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	existingFlags, err := ffs.Backend.GetAllFeatureFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve existing feature flags: %w", err)
+	}
+	remoteFlagMap := make(map[string]config.FeatureFlag, len(existingFlags))
+	for _, ef := range existingFlags {
+		remoteFlagMap[ef.Name] = ef
+	}
+
+	if len(names) == 0 {
+		for _, flag := range ffs.Selection.filter(flags) {
+			if remoteFlag, exists := remoteFlagMap[flag.Name]; exists && !isManaged(remoteFlag.Description) {
+				names = append(names, flag.Name)
+			}
+		}
+	}
+
+	var toAdopt []config.FeatureFlag
+	for _, name := range names {
+		remoteFlag, exists := remoteFlagMap[name]
+		if !exists {
+			return fmt.Errorf("feature flag %s does not exist in GitLab", name)
+		}
+		if isManaged(remoteFlag.Description) {
+			log.Printf("Flag %s is already managed, skipping", name)
+			continue
+		}
+		remoteFlag.Description = markManaged(remoteFlag.Description)
+		toAdopt = append(toAdopt, remoteFlag)
+	}
+
+	log.Printf("Flags to adopt: %d", len(toAdopt))
+	if ffs.DryRun {
+		for _, flag := range toAdopt {
+			fmt.Fprintf(ffs.out(), "would adopt %s\n", flag.Name)
+		}
+		return nil
+	}
+
+	results := processFlagsConcurrently(ctx, ActionAdopt, toAdopt, configFlagName, ffs.Backend.UpdateFeatureFlag, maxConcurrency, ffs.stopOnError())
+	if err := collectFailures(results); err != nil {
+		return fmt.Errorf("failed to adopt feature flags: %w", err)
+	}
+	log.Printf("Adopted %d flags successfully", len(toAdopt))
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagedMarker(t *testing.T) {
+	assert.Equal(t, config.ManagedMarker, markManaged(""))
+	assert.Equal(t, "Beta "+config.ManagedMarker, markManaged("Beta"))
+	assert.Equal(t, "Beta "+config.ManagedMarker, markManaged(markManaged("Beta")))
+
+	assert.True(t, isManaged("Beta "+config.ManagedMarker))
+	assert.True(t, isManaged(config.ManagedMarker+"\n"))
+	assert.False(t, isManaged("Beta"))
+
+	assert.Equal(t, "Beta", unmarkManaged("Beta "+config.ManagedMarker))
+	assert.Equal(t, "", unmarkManaged(config.ManagedMarker))
+	assert.Equal(t, "Beta ", unmarkManaged("Beta "))
+}
+
+func TestAdoptFeatureFlags(t *testing.T) {
+	remote := []config.FeatureFlag{
+		{Name: "manual", Description: "created in UI", Active: true},
+		{Name: "owned", Description: "ours " + config.ManagedMarker, Active: true},
+		{Name: "other", Description: "someone else's", Active: true},
+	}
+
+	var mu sync.Mutex
+	updated := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/1/feature_flags":
+			w.Header().Set("X-Total-Pages", "1")
+			require.NoError(t, json.NewEncoder(w).Encode(remote))
+		case r.Method == http.MethodGet:
+			name := strings.TrimPrefix(r.URL.Path, "/projects/1/feature_flags/")
+			for _, flag := range remote {
+				if flag.Name == name {
+					require.NoError(t, json.NewEncoder(w).Encode(flag))
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut:
+			var body config.FeatureFlag
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			mu.Lock()
+			updated[body.Name] = body.Description
+			mu.Unlock()
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ffs := FeatureFlagService{Backend: client.NewGitLabClient(server.URL, "token", "1", 10)}
+
+	t.Run("flags_from_config", func(t *testing.T) {
+		updated = map[string]string{}
+		err := ffs.AdoptFeatureFlags(nil, []config.FeatureFlag{{Name: "manual"}, {Name: "owned"}, {Name: "missing"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"manual": "created in UI " + config.ManagedMarker}, updated)
+	})
+
+	t.Run("explicit_names", func(t *testing.T) {
+		updated = map[string]string{}
+		err := ffs.AdoptFeatureFlags([]string{"other"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"other": "someone else's " + config.ManagedMarker}, updated)
+	})
+
+	t.Run("unknown_flag", func(t *testing.T) {
+		err := ffs.AdoptFeatureFlags([]string{"missing"}, nil)
+		assert.EqualError(t, err, "feature flag missing does not exist in GitLab")
+	})
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// Plan набор изменений, который синхронизация применит к GitLab
+type Plan struct {
+	FormatVersion int `json:"format_version"`
+	// Fingerprint отпечаток флагов и списков пользователей GitLab, по которым построен план
+	Fingerprint string `json:"fingerprint"`
+
+	Create []config.FeatureFlag `json:"create"`
+	Update []FlagUpdate         `json:"update"`
+	Delete []string             `json:"delete"`
+	// Orphaned управляемые флаги GitLab, которых нет в конфигурации; без Prune они сохраняются
+	Orphaned []string `json:"orphaned,omitempty"`
+	// Unmanaged флаги из конфигурации, созданные в GitLab вручную; они пропускаются
+	Unmanaged []string `json:"unmanaged,omitempty"`
+	// RemoteCount число управляемых флагов в GitLab на момент построения плана
+	RemoteCount int `json:"remote_count"`
+
+	// Изменения списков пользователей: списки создаются и изменяются до флагов, а удаляются после них
+	CreateUserLists []config.UserList `json:"create_user_lists,omitempty"`
+	UpdateUserLists []UserListUpdate  `json:"update_user_lists,omitempty"`
+	DeleteUserLists []string          `json:"delete_user_lists,omitempty"`
+	// RemoteUserListCount число списков пользователей в GitLab на момент построения плана
+	RemoteUserListCount int `json:"remote_user_list_count,omitempty"`
+}
+
+// UserListUpdate список пользователей, который будет изменён
+type UserListUpdate struct {
+	List    config.UserList `json:"list"`
+	Changes []FieldChange   `json:"changes"`
+}
+
+// FlagUpdate флаг, который будет изменён, вместе с отличиями от удалённого состояния
+type FlagUpdate struct {
+	Flag    config.FeatureFlag `json:"flag"`
+	Changes []FieldChange      `json:"changes"`
+}
+
+// FieldChange отличие одного поля флага
+type FieldChange struct {
+	Field   string `json:"field"`
+	Remote  string `json:"remote"`
+	Desired string `json:"desired"`
+}
+
+func (p *Plan) Empty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0 && p.userListsLen() == 0
+}
+
+func (p *Plan) userListsLen() int {
+	return len(p.CreateUserLists) + len(p.UpdateUserLists) + len(p.DeleteUserLists)
+}
+
+// Len число всех изменений плана, включая списки пользователей
+func (p *Plan) Len() int {
+	return len(p.Create) + len(p.Update) + len(p.Delete) + p.userListsLen()
+}
+
+// WriteReport печатает человекочитаемый отчёт о запланированных изменениях
+func (p *Plan) WriteReport(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete\n", len(p.Create), len(p.Update), len(p.Delete))
+	if p.userListsLen() > 0 {
+		fmt.Fprintf(&b, "User lists: %d to create, %d to update, %d to delete\n", len(p.CreateUserLists), len(p.UpdateUserLists), len(p.DeleteUserLists))
+	}
+	if p.Empty() {
+		b.WriteString("\nNo changes. Remote feature flags match the configuration.\n")
+	}
+
+	createLists := append([]config.UserList(nil), p.CreateUserLists...)
+	sort.Slice(createLists, func(i, j int) bool { return createLists[i].Name < createLists[j].Name })
+	for _, list := range createLists {
+		fmt.Fprintf(&b, "\n+ user list %s\n", list.Name)
+		fmt.Fprintf(&b, "    user_xids: %s\n", formatUserXIDs(list.UserXIDs))
+	}
+
+	updateLists := append([]UserListUpdate(nil), p.UpdateUserLists...)
+	sort.Slice(updateLists, func(i, j int) bool { return updateLists[i].List.Name < updateLists[j].List.Name })
+	for _, u := range updateLists {
+		fmt.Fprintf(&b, "\n~ user list %s\n", u.List.Name)
+		for _, change := range u.Changes {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", change.Field, change.Remote, change.Desired)
+		}
+	}
+
+	create := append([]config.FeatureFlag(nil), p.Create...)
+	sort.Slice(create, func(i, j int) bool { return create[i].Name < create[j].Name })
+	for _, flag := range create {
+		fmt.Fprintf(&b, "\n+ %s\n", flag.Name)
+		fmt.Fprintf(&b, "    description: %s\n", quote(flag.Description))
+		fmt.Fprintf(&b, "    active: %t\n", flag.Active)
+		for i, strategy := range flag.Strategies {
+			fmt.Fprintf(&b, "    strategies[%d]: %s\n", i, normalizeStrategy(strategy))
+		}
+	}
+
+	update := append([]FlagUpdate(nil), p.Update...)
+	sort.Slice(update, func(i, j int) bool { return update[i].Flag.Name < update[j].Flag.Name })
+	for _, u := range update {
+		fmt.Fprintf(&b, "\n~ %s\n", u.Flag.Name)
+		for _, change := range u.Changes {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", change.Field, change.Remote, change.Desired)
+		}
+	}
+
+	del := append([]string(nil), p.Delete...)
+	sort.Strings(del)
+	for _, name := range del {
+		fmt.Fprintf(&b, "\n- %s\n", name)
+	}
+
+	deleteLists := append([]string(nil), p.DeleteUserLists...)
+	sort.Strings(deleteLists)
+	for _, name := range deleteLists {
+		fmt.Fprintf(&b, "\n- user list %s\n", name)
+	}
+
+	orphaned := append([]string(nil), p.Orphaned...)
+	sort.Strings(orphaned)
+	for _, name := range orphaned {
+		fmt.Fprintf(&b, "\n? %s (not in config, kept; pass -prune to delete it)\n", name)
+	}
+
+	unmanaged := append([]string(nil), p.Unmanaged...)
+	sort.Strings(unmanaged)
+	for _, name := range unmanaged {
+		fmt.Fprintf(&b, "\n! %s (exists in GitLab but is not managed, run adopt to take it over)\n", name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// diffFlags возвращает семантические отличия desired от remote по полям.
+// Флаги сравниваются в канонической форме, стратегии сопоставляются без учёта порядка.
+// Отсутствующее значение обозначается как "<none>".
+func diffFlags(remote, desired config.FeatureFlag) []FieldChange {
+	r, d := normalizeFlag(remote), normalizeFlag(desired)
+
+	var changes []FieldChange
+	add := func(field, rv, dv string) {
+		if rv != dv {
+			changes = append(changes, FieldChange{Field: field, Remote: rv, Desired: dv})
+		}
+	}
+
+	add("description", quote(r.Description), quote(d.Description))
+	add("active", fmt.Sprint(r.Active), fmt.Sprint(d.Active))
+
+	labels := make(map[string]int)
+	for _, pair := range pairStrategies(r.Strategies, d.Strategies) {
+		var name string
+		if pair.desired != nil {
+			name = pair.desired.Name
+		} else {
+			name = pair.remote.Name
+		}
+		labels[name]++
+		prefix := fmt.Sprintf("strategies[%s]", name)
+		if labels[name] > 1 {
+			prefix = fmt.Sprintf("strategies[%s#%d]", name, labels[name])
+		}
+
+		switch {
+		case pair.remote == nil:
+			add(prefix, noneValue, pair.desired.String())
+		case pair.desired == nil:
+			add(prefix, pair.remote.String(), noneValue)
+		default:
+			for _, key := range unionKeys(pair.remote.Parameters, pair.desired.Parameters) {
+				add(prefix+".parameters."+key, formatParameter(pair.remote.Parameters, key), formatParameter(pair.desired.Parameters, key))
+			}
+			add(prefix+".scopes", formatScopes(pair.remote.Scopes), formatScopes(pair.desired.Scopes))
+			add(prefix+".user_list", quote(pair.remote.UserList), quote(pair.desired.UserList))
+		}
+	}
+
+	return changes
+}
+
+func flagsEqual(a, b config.FeatureFlag) bool {
+	return len(diffFlags(a, b)) == 0
+}
+
+type strategyPair struct {
+	remote  *normalizedStrategy
+	desired *normalizedStrategy
+}
+
+// pairStrategies сопоставляет отличающиеся стратегии: сначала отбрасываются совпадающие,
+// затем оставшиеся сопоставляются по имени. Несопоставленные стратегии возвращаются без пары.
+func pairStrategies(remote, desired []normalizedStrategy) []strategyPair {
+	usedRemote := make([]bool, len(remote))
+	usedDesired := make([]bool, len(desired))
+
+	for i := range desired {
+		for j := range remote {
+			if !usedRemote[j] && remote[j].equal(desired[i]) {
+				usedRemote[j], usedDesired[i] = true, true
+				break
+			}
+		}
+	}
+
+	var pairs []strategyPair
+	for i := range desired {
+		if usedDesired[i] {
+			continue
+		}
+		pair := strategyPair{desired: &desired[i]}
+		for j := range remote {
+			if !usedRemote[j] && remote[j].Name == desired[i].Name {
+				usedRemote[j] = true
+				pair.remote = &remote[j]
+				break
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+	for j := range remote {
+		if !usedRemote[j] {
+			pairs = append(pairs, strategyPair{remote: &remote[j]})
+		}
+	}
+	return pairs
+}
+
+const noneValue = "<none>"
+
+func quote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
+
+func formatParameter(params map[string]string, key string) string {
+	value, ok := params[key]
+	if !ok {
+		return noneValue
+	}
+	return quote(value)
+}
+
+func formatScopes(scopes []string) string {
+	return "[" + strings.Join(scopes, ", ") + "]"
+}
+
+func unionKeys(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, m := range []map[string]string{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
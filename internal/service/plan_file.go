@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// planFormatVersion версия формата сохранённого плана
+const planFormatVersion = 2
+
+// fingerprint вычисляет отпечаток удалённого состояния флагов и списков пользователей,
+// не зависящий от порядка их получения
+func fingerprint(flags []config.FeatureFlag, lists []config.UserList) (string, error) {
+	sortedFlags := append([]config.FeatureFlag(nil), flags...)
+	sort.Slice(sortedFlags, func(i, j int) bool { return sortedFlags[i].Name < sortedFlags[j].Name })
+	sortedLists := append([]config.UserList(nil), lists...)
+	sort.Slice(sortedLists, func(i, j int) bool { return sortedLists[i].Name < sortedLists[j].Name })
+
+	data, err := json.Marshal(struct {
+		Flags     []config.FeatureFlag `json:"flags"`
+		UserLists []config.UserList    `json:"user_lists"`
+	}{sortedFlags, sortedLists})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute remote fingerprint: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WritePlanFile сохраняет план в JSON файл для последующего apply
+func WritePlanFile(fileName string, plan *Plan) error {
+	plan.FormatVersion = planFormatVersion
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling plan: %w", err)
+	}
+	if err := os.WriteFile(fileName, data, 0o644); err != nil {
+		return fmt.Errorf("error writing plan file: %w", err)
+	}
+	return nil
+}
+
+func ReadPlanFile(fileName string) (*Plan, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("error unmarshalling plan: %w", err)
+	}
+	if plan.FormatVersion != planFormatVersion {
+		return nil, fmt.Errorf("unsupported plan format version %d, expected %d", plan.FormatVersion, planFormatVersion)
+	}
+	if plan.Fingerprint == "" {
+		return nil, fmt.Errorf("plan file has no remote fingerprint")
+	}
+	return &plan, nil
+}
+
+// ApplyPlanFile применяет сохранённый план, если флаги и списки пользователей в GitLab
+// не изменились с момента его создания. В режиме DryRun план только проверяется и печатается в Out.
+func (ffs *FeatureFlagService) ApplyPlanFile(fileName string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	plan, err := ReadPlanFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	existingFlags, err := ffs.Backend.GetAllFeatureFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve existing feature flags: %w", err)
+	}
+	existingLists, err := ffs.Backend.GetAllUserLists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve existing user lists: %w", err)
+	}
+	current, err := fingerprint(existingFlags, existingLists)
+	if err != nil {
+		return err
+	}
+	if current != plan.Fingerprint {
+		return fmt.Errorf("remote feature flags or user lists changed since the plan was created (fingerprint %s, plan %s), run plan again", current, plan.Fingerprint)
+	}
+
+	if ffs.DryRun {
+		log.Printf("Dry run: plan %s still matches GitLab, no changes will be applied", fileName)
+		if err := plan.WriteReport(ffs.out()); err != nil {
+			return err
+		}
+		return ffs.checkDeletionLimit(plan)
+	}
+
+	log.Printf("Applying plan %s: %d to create, %d to update, %d to delete", fileName, len(plan.Create), len(plan.Update), len(plan.Delete))
+	_, err = ffs.ApplyPlan(ctx, plan)
+	return err
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanFile(t *testing.T) {
+	desired := []config.FeatureFlag{
+		{Name: "existing", Description: "changed", Active: false},
+		{Name: "fresh", Description: "new flag", Active: true},
+	}
+	remote := config.FeatureFlag{Name: "existing", Description: "original " + config.ManagedMarker, Active: true}
+
+	t.Run("apply_unchanged_remote", func(t *testing.T) {
+		gitlab := newFakeGitLab(t, remote)
+		planFile := filepath.Join(t.TempDir(), "plan.json")
+
+		planner := FeatureFlagService{Backend: gitlab.client(), DryRun: true, PlanOut: planFile, Out: io.Discard}
+		require.NoError(t, planner.SyncFeatureFlags(desired))
+
+		plan, err := ReadPlanFile(planFile)
+		require.NoError(t, err)
+		assert.Len(t, plan.Create, 1)
+		assert.Len(t, plan.Update, 1)
+		assert.NotEmpty(t, plan.Fingerprint)
+
+		applier := FeatureFlagService{Backend: gitlab.client()}
+		require.NoError(t, applier.ApplyPlanFile(planFile))
+
+		flags := gitlab.snapshot()
+		assert.Equal(t, "changed "+config.ManagedMarker, flags["existing"].Description)
+		assert.False(t, flags["existing"].Active)
+		assert.Equal(t, "new flag "+config.ManagedMarker, flags["fresh"].Description)
+	})
+
+	t.Run("dry_run_does_not_apply", func(t *testing.T) {
+		gitlab := newFakeGitLab(t, remote)
+		planFile := filepath.Join(t.TempDir(), "plan.json")
+
+		planner := FeatureFlagService{Backend: gitlab.client(), DryRun: true, PlanOut: planFile, Out: io.Discard}
+		require.NoError(t, planner.SyncFeatureFlags(desired))
+
+		var out bytes.Buffer
+		applier := FeatureFlagService{Backend: gitlab.client(), DryRun: true, Out: &out}
+		require.NoError(t, applier.ApplyPlanFile(planFile))
+
+		assert.Contains(t, out.String(), "Plan: 1 to create, 1 to update, 0 to delete")
+		assert.Equal(t, map[string]config.FeatureFlag{"existing": remote}, gitlab.snapshot())
+	})
+
+	t.Run("refuse_changed_remote", func(t *testing.T) {
+		gitlab := newFakeGitLab(t, remote)
+		planFile := filepath.Join(t.TempDir(), "plan.json")
+
+		planner := FeatureFlagService{Backend: gitlab.client(), DryRun: true, PlanOut: planFile, Out: io.Discard}
+		require.NoError(t, planner.SyncFeatureFlags(desired))
+
+		toggled := remote
+		toggled.Active = false
+		gitlab.set(toggled)
+
+		applier := FeatureFlagService{Backend: gitlab.client()}
+		err := applier.ApplyPlanFile(planFile)
+		assert.ErrorContains(t, err, "remote feature flags or user lists changed since the plan was created")
+		assert.NotContains(t, gitlab.snapshot(), "fresh")
+	})
+
+	t.Run("refuse_changed_user_lists", func(t *testing.T) {
+		ctx := context.Background()
+		memory := backend.NewMemory().AddUserLists(config.UserList{Name: "beta", UserXIDs: []string{"alice"}})
+		planFile := filepath.Join(t.TempDir(), "plan.json")
+
+		planner := FeatureFlagService{Backend: memory, DryRun: true, PlanOut: planFile, Out: io.Discard,
+			UserLists: []config.UserList{{Name: "beta", UserXIDs: []string{"alice", "bob"}}}}
+		require.NoError(t, planner.SyncFeatureFlags(nil))
+
+		require.NoError(t, memory.UpdateUserList(ctx, config.UserList{Name: "beta", UserXIDs: []string{"carol"}}))
+
+		applier := FeatureFlagService{Backend: memory}
+		err := applier.ApplyPlanFile(planFile)
+		assert.ErrorContains(t, err, "remote feature flags or user lists changed since the plan was created")
+		lists, err := memory.GetAllUserLists(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []config.UserList{{Name: "beta", UserXIDs: []string{"carol"}}}, lists)
+	})
+
+	t.Run("unsupported_version", func(t *testing.T) {
+		planFile := filepath.Join(t.TempDir(), "plan.json")
+		require.NoError(t, os.WriteFile(planFile, []byte(`{"format_version": 99, "fingerprint": "abc"}`), 0o644))
+
+		_, err := ReadPlanFile(planFile)
+		assert.EqualError(t, err, "unsupported plan format version 99, expected 2")
+	})
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffFlags(t *testing.T) {
+	remote := config.FeatureFlag{
+		Name:        "flag1",
+		Description: "old",
+		Active:      true,
+		Strategies: []config.Strategy{
+			{
+				Name:       "userWithId",
+				Parameters: map[string]interface{}{"userIds": "1,2"},
+				Scopes:     []config.Scope{{Environment: "PROD"}},
+			},
+		},
+	}
+	desired := config.FeatureFlag{
+		Name:        "flag1",
+		Description: "new",
+		Active:      true,
+		Strategies: []config.Strategy{
+			{
+				Name:       "userWithId",
+				Parameters: map[string]interface{}{"userIds": "1,2,3"},
+				Scopes:     []config.Scope{{Environment: "TEST"}, {Environment: "PROD"}},
+			},
+			{
+				Name:       "default",
+				Parameters: map[string]interface{}{},
+				Scopes:     []config.Scope{{Environment: "*"}},
+			},
+		},
+	}
+
+	changes := diffFlags(remote, desired)
+
+	assert.Equal(t, []FieldChange{
+		{Field: "description", Remote: `"old"`, Desired: `"new"`},
+		{Field: "strategies[default]", Remote: "<none>", Desired: "default() [*]"},
+		{Field: "strategies[userWithId].parameters.userIds", Remote: `"1,2"`, Desired: `"1,2,3"`},
+		{Field: "strategies[userWithId].scopes", Remote: "[PROD]", Desired: "[PROD, TEST]"},
+	}, changes)
+	assert.Empty(t, diffFlags(desired, desired))
+}
+
+func TestSyncFeatureFlagsDryRun(t *testing.T) {
+	remote := []config.FeatureFlag{
+		{Name: "keep", Description: "same " + config.ManagedMarker, Active: true},
+		{Name: "change", Description: "old " + config.ManagedMarker, Active: true},
+		{Name: "obsolete", Description: "gone " + config.ManagedMarker, Active: false},
+		{Name: "manual", Description: "created in UI", Active: true},
+		{Name: "handmade", Description: "created in UI", Active: true},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method, "dry run must not modify GitLab")
+		w.Header().Set("X-Total-Pages", "1")
+		w.WriteHeader(http.StatusOK)
+		require.NoError(t, json.NewEncoder(w).Encode(remote))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	ffs := FeatureFlagService{
+		Backend: client.NewGitLabClient(server.URL, "token", "1", 10),
+		DryRun:  true,
+		Prune:   true,
+		Out:     &out,
+	}
+
+	err := ffs.SyncFeatureFlags([]config.FeatureFlag{
+		{Name: "keep", Description: "same", Active: true},
+		{Name: "change", Description: "new", Active: false},
+		{Name: "fresh", Description: "brand new", Active: true},
+		{Name: "manual", Description: "created in UI", Active: false},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `Plan: 1 to create, 1 to update, 1 to delete
+
++ fresh
+    description: "brand new"
+    active: true
+
+~ change
+    description: "old" -> "new"
+    active: true -> false
+
+- obsolete
+
+! manual (exists in GitLab but is not managed, run adopt to take it over)
+`, out.String())
+}
+
+func TestPlanRejectsDuplicateFlags(t *testing.T) {
+	ffs := FeatureFlagService{Backend: newFakeGitLab(t).client()}
+
+	_, err := ffs.PlanFeatureFlags(context.Background(), []config.FeatureFlag{{Name: "flag1"}, {Name: "flag1", Active: true}})
+
+	assert.EqualError(t, err, "feature flag flag1 is defined more than once")
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DeletionLimit максимальное число удалений за одну синхронизацию:
+// абсолютное ("10") или в процентах от числа удалённых флагов ("25%")
+type DeletionLimit struct {
+	Value   int
+	Percent bool
+}
+
+func ParseDeletionLimit(s string) (DeletionLimit, error) {
+	raw := strings.TrimSpace(s)
+	percent := strings.HasSuffix(raw, "%")
+	value, err := strconv.Atoi(strings.TrimSuffix(raw, "%"))
+	if err != nil || value < 0 {
+		return DeletionLimit{}, fmt.Errorf("invalid deletion limit %q: expected a non-negative number or percentage", s)
+	}
+	if percent && value > 100 {
+		return DeletionLimit{}, fmt.Errorf("invalid deletion limit %q: percentage must not exceed 100%%", s)
+	}
+	return DeletionLimit{Value: value, Percent: percent}, nil
+}
+
+// Max возвращает допустимое число удалений при total флагах в GitLab
+func (l DeletionLimit) Max(total int) int {
+	if l.Percent {
+		return total * l.Value / 100
+	}
+	return l.Value
+}
+
+func (l DeletionLimit) String() string {
+	if l.Percent {
+		return fmt.Sprintf("%d%%", l.Value)
+	}
+	return strconv.Itoa(l.Value)
+}
+
+// checkDeletionLimit отказывает в удалении, если план превышает MaxDeletes и не указан Force.
+// Флаги и списки пользователей ограничиваются по отдельности, каждый от своего числа в GitLab.
+func (ffs *FeatureFlagService) checkDeletionLimit(plan *Plan) error {
+	if ffs.MaxDeletes == nil || ffs.Force {
+		return nil
+	}
+	if err := ffs.checkDeletions("feature flags", plan.Delete, plan.RemoteCount); err != nil {
+		return err
+	}
+	return ffs.checkDeletions("user lists", plan.DeleteUserLists, plan.RemoteUserListCount)
+}
+
+func (ffs *FeatureFlagService) checkDeletions(kind string, deletions []string, total int) error {
+	allowed := ffs.MaxDeletes.Max(total)
+	if len(deletions) <= allowed {
+		return nil
+	}
+
+	names := append([]string(nil), deletions...)
+	sort.Strings(names)
+	return fmt.Errorf(
+		"refusing to delete %d of %d remote %s (limit %s allows %d), pass -force to delete anyway: %s",
+		len(names), total, kind, ffs.MaxDeletes, allowed, strings.Join(names, ", "),
+	)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeletionLimit(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected DeletionLimit
+		total    int
+		max      int
+		err      bool
+	}{
+		{input: "10", expected: DeletionLimit{Value: 10}, total: 100, max: 10},
+		{input: "0", expected: DeletionLimit{Value: 0}, total: 100, max: 0},
+		{input: "25%", expected: DeletionLimit{Value: 25, Percent: true}, total: 10, max: 2},
+		{input: " 100% ", expected: DeletionLimit{Value: 100, Percent: true}, total: 7, max: 7},
+		{input: "101%", err: true},
+		{input: "-1", err: true},
+		{input: "many", err: true},
+		{input: "", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			limit, err := ParseDeletionLimit(tc.input)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, limit)
+			assert.Equal(t, tc.max, limit.Max(tc.total))
+		})
+	}
+}
+
+func TestCheckDeletionLimit(t *testing.T) {
+	plan := &Plan{Delete: []string{"c", "a", "b"}, RemoteCount: 10}
+
+	t.Run("no_limit", func(t *testing.T) {
+		ffs := FeatureFlagService{}
+		assert.NoError(t, ffs.checkDeletionLimit(plan))
+	})
+
+	t.Run("within_limit", func(t *testing.T) {
+		ffs := FeatureFlagService{MaxDeletes: &DeletionLimit{Value: 30, Percent: true}}
+		assert.NoError(t, ffs.checkDeletionLimit(plan))
+	})
+
+	t.Run("exceeds_limit", func(t *testing.T) {
+		ffs := FeatureFlagService{MaxDeletes: &DeletionLimit{Value: 2}}
+		err := ffs.checkDeletionLimit(plan)
+		assert.EqualError(t, err, "refusing to delete 3 of 10 remote feature flags (limit 2 allows 2), pass -force to delete anyway: a, b, c")
+	})
+
+	t.Run("forced", func(t *testing.T) {
+		ffs := FeatureFlagService{MaxDeletes: &DeletionLimit{Value: 2}, Force: true}
+		assert.NoError(t, ffs.checkDeletionLimit(plan))
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nkrus/gitlab-flagman/internal/client"
+	"github.com/nkrus/gitlab-flagman/internal/flagstore"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionAdopt  Action = "adopt"
+)
+
+// Result итог обработки одного флага
+type Result struct {
+	Action Action
+	Flag   string
+	Err    error
+	// StatusCode HTTP статус ответа GitLab, если запрос завершился ошибкой статуса
+	StatusCode int
+	Duration   time.Duration
+	// Skipped флаг не обрабатывался, потому что синхронизация остановилась на предыдущей ошибке
+	Skipped bool
+}
+
+func (r Result) Failed() bool {
+	return r.Err != nil
+}
+
+func (r Result) String() string {
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%s %s: skipped", r.Action, r.Flag)
+	case r.Err == nil:
+		return fmt.Sprintf("%s %s: ok (%s)", r.Action, r.Flag, r.Duration.Round(time.Millisecond))
+	case r.StatusCode != 0:
+		return fmt.Sprintf("%s %s: failed with status %d (%s): %v", r.Action, r.Flag, r.StatusCode, r.Duration.Round(time.Millisecond), r.Err)
+	default:
+		return fmt.Sprintf("%s %s: failed (%s): %v", r.Action, r.Flag, r.Duration.Round(time.Millisecond), r.Err)
+	}
+}
+
+// SyncError перечисляет все флаги, которые не удалось обработать
+type SyncError struct {
+	Failures []Result
+}
+
+func (e *SyncError) Error() string {
+	lines := make([]string, 0, len(e.Failures)+1)
+	lines = append(lines, fmt.Sprintf("%d feature flags failed:", len(e.Failures)))
+	for _, failure := range e.Failures {
+		lines = append(lines, "\t"+failure.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newResult(action Action, flag string, err error, duration time.Duration) Result {
+	result := Result{Action: action, Flag: flag, Err: err, Duration: duration}
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		result.StatusCode = apiErr.StatusCode
+	}
+	return result
+}
+
+// fatalError возвращает причину, по которой остальные запросы к GitLab тоже не пройдут,
+// или пустую строку. Такие ошибки останавливают синхронизацию даже с ContinueOnError.
+func fatalError(err error) string {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch {
+	case apiErr.Unauthorized():
+		return "GitLab rejected the token"
+	case apiErr.Forbidden():
+		return "the token has no access to feature flags of the project"
+	case apiErr.RateLimited():
+		return "GitLab rate limit is still exceeded after retries"
+	}
+	return ""
+}
+
+// rejected сообщает, что GitLab отклонил запрос как некорректный и ничего не изменил
+func rejected(err error) bool {
+	var apiErr *client.APIError
+	return errors.As(err, &apiErr) && apiErr.Validation()
+}
+
+// ErrNotFound флаг или список пользователей отсутствует в хранилище.
+// С ней совпадают через errors.Is ошибки GitLab со статусом 404 и ошибки локальных бэкендов.
+var ErrNotFound = flagstore.ErrNotFound
+
+// collectFailures логирует результаты и возвращает SyncError, если среди них есть ошибки
+func collectFailures(results []Result) error {
+	var failures []Result
+	skipped := 0
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			skipped++
+		case result.Failed():
+			failures = append(failures, result)
+		}
+		log.Println(result)
+	}
+	if skipped > 0 {
+		log.Printf("Flags skipped after a failure: %d (pass -continue-on-error to process them anyway)", skipped)
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return &SyncError{Failures: failures}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/backend"
+	"github.com/nkrus/gitlab-flagman/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPlanResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/broken-delete"):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	plan := &Plan{
+		Delete: []string{"broken-delete"},
+		Create: []config.FeatureFlag{{Name: "new1"}, {Name: "new2"}},
+	}
+
+	t.Run("stop_on_first_error", func(t *testing.T) {
+		ffs := FeatureFlagService{Backend: client.NewGitLabClient(server.URL, "token", "1", 10)}
+
+		results, err := ffs.ApplyPlan(context.Background(), plan)
+
+		require.Len(t, results, 3)
+		assert.Equal(t, ActionDelete, results[0].Action)
+		assert.Equal(t, http.StatusUnprocessableEntity, results[0].StatusCode)
+		assert.True(t, results[1].Skipped)
+		assert.True(t, results[2].Skipped)
+
+		var syncErr *SyncError
+		require.True(t, errors.As(err, &syncErr))
+		require.Len(t, syncErr.Failures, 1)
+		assert.Equal(t, "broken-delete", syncErr.Failures[0].Flag)
+	})
+
+	t.Run("continue_on_error", func(t *testing.T) {
+		ffs := FeatureFlagService{
+			Backend:         client.NewGitLabClient(server.URL, "token", "1", 10),
+			ContinueOnError: true,
+		}
+
+		results, err := ffs.ApplyPlan(context.Background(), plan)
+
+		require.Len(t, results, 3)
+		for _, result := range results {
+			assert.False(t, result.Skipped)
+			assert.True(t, result.Failed())
+		}
+		assert.Equal(t, http.StatusBadRequest, results[1].StatusCode)
+
+		var syncErr *SyncError
+		require.True(t, errors.As(err, &syncErr))
+		assert.Len(t, syncErr.Failures, 3)
+		assert.Contains(t, err.Error(), "3 feature flags failed:")
+		assert.Contains(t, err.Error(), "create new1: failed with status 400")
+		assert.Contains(t, err.Error(), "create new2: failed with status 400")
+		assert.Contains(t, err.Error(), "delete broken-delete: failed with status 422")
+	})
+}
+
+func TestApplyPlanStopsOnAccessErrors(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			gitlab := newFakeGitLab(t, config.FeatureFlag{Name: "old", Description: markManaged("")})
+			gitlab.fail = func(r *http.Request) int {
+				return status
+			}
+			ffs := FeatureFlagService{Backend: gitlab.client(), ContinueOnError: true}
+
+			plan := &Plan{
+				Delete: []string{"old"},
+				Create: []config.FeatureFlag{{Name: "new1"}, {Name: "new2"}},
+			}
+			results, err := ffs.ApplyPlan(context.Background(), plan)
+
+			require.Error(t, err)
+			require.Len(t, results, 3)
+			assert.Equal(t, status, results[0].StatusCode)
+			assert.True(t, results[1].Skipped)
+			assert.True(t, results[2].Skipped)
+		})
+	}
+}
+
+func TestUpdateRecreatesDeletedFlag(t *testing.T) {
+	backends := map[string]FeatureFlagBackend{
+		"gitlab": newFakeGitLab(t).client(),
+		"memory": backend.NewMemory(),
+		"file":   backend.NewFile(filepath.Join(t.TempDir(), "flags.json")),
+	}
+
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			ffs := FeatureFlagService{Backend: b}
+
+			flag := config.FeatureFlag{Name: "flag1", Description: "desc", Active: true}
+			results, err := ffs.ApplyPlan(context.Background(), &Plan{Update: []FlagUpdate{{Flag: flag}}})
+
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.False(t, results[0].Failed())
+			recreated, err := b.GetFeatureFlag(context.Background(), "flag1")
+			require.NoError(t, err)
+			assert.Equal(t, markManaged("desc"), recreated.Description)
+		})
+	}
+}
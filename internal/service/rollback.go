@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+const ActionRestore Action = "restore"
+
+// RollbackError перечисляет флаги, которые не удалось вернуть в исходное состояние
+type RollbackError struct {
+	Failures []Result
+}
+
+func (e *RollbackError) Error() string {
+	lines := make([]string, 0, len(e.Failures)+1)
+	lines = append(lines, fmt.Sprintf("rollback could not restore %d feature flags:", len(e.Failures)))
+	for _, failure := range e.Failures {
+		lines = append(lines, "\t"+failure.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// snapshotFeatureFlags сохраняет состояние всех флагов GitLab перед изменениями
+func (ffs *FeatureFlagService) snapshotFeatureFlags(ctx context.Context) (map[string]config.FeatureFlag, error) {
+	existingFlags, err := ffs.Backend.GetAllFeatureFlags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot remote feature flags: %w", err)
+	}
+	snapshot := make(map[string]config.FeatureFlag, len(existingFlags))
+	for _, flag := range existingFlags {
+		snapshot[flag.Name] = flag
+	}
+	return snapshot, nil
+}
+
+// rollback возвращает затронутые синхронизацией флаги к состоянию snapshot.
+// Каждый флаг сверяется с текущим состоянием GitLab, поэтому частично применённые
+// операции тоже откатываются: лишние флаги удаляются, удалённые создаются заново,
+// изменённые перезаписываются.
+func (ffs *FeatureFlagService) rollback(ctx context.Context, snapshot map[string]config.FeatureFlag, results []Result) ([]Result, error) {
+	// Откат выполняется даже если исходный контекст уже отменён.
+	// Списки пользователей не откатываются: созданные остаются, удаляются только неиспользуемые.
+	ctx = context.WithoutCancel(ctx)
+
+	touched := make(map[string]bool)
+	var names []string
+	for _, result := range results {
+		// Флаги, изменение которых GitLab отклонил при проверке, остались прежними
+		if result.Skipped || rejected(result.Err) || isUserListAction(result.Action) || touched[result.Flag] {
+			continue
+		}
+		touched[result.Flag] = true
+		names = append(names, result.Flag)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	log.Printf("Rolling back %d touched flags", len(names))
+
+	currentFlags, err := ffs.Backend.GetAllFeatureFlags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rollback failed to retrieve current feature flags: %w", err)
+	}
+	current := make(map[string]config.FeatureFlag, len(currentFlags))
+	for _, flag := range currentFlags {
+		current[flag.Name] = flag
+	}
+
+	restore := func(ctx context.Context, name string) error {
+		original, existed := snapshot[name]
+		now, exists := current[name]
+		switch {
+		case existed && exists:
+			if flagsEqual(now, original) {
+				return nil
+			}
+			return ffs.Backend.UpdateFeatureFlag(ctx, original)
+		case existed:
+			return ffs.Backend.CreateFeatureFlag(ctx, original)
+		case exists:
+			return ffs.Backend.DeleteFeatureFlag(ctx, name)
+		default:
+			return nil
+		}
+	}
+
+	restored := processFlagsConcurrently(ctx, ActionRestore, names, flagName, restore, maxConcurrency, nil)
+
+	var failures []Result
+	for _, result := range restored {
+		log.Println(result)
+		if result.Failed() {
+			failures = append(failures, result)
+		}
+	}
+	log.Printf("Rollback restored %d of %d flags", len(restored)-len(failures), len(restored))
+
+	if len(failures) > 0 {
+		return restored, &RollbackError{Failures: failures}
+	}
+	return restored, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPlanRollback(t *testing.T) {
+	initial := []config.FeatureFlag{
+		{Name: "obsolete", Description: "to delete " + config.ManagedMarker, Active: true},
+		{Name: "changed", Description: "before " + config.ManagedMarker, Active: true},
+		{Name: "broken", Description: "before " + config.ManagedMarker, Active: true},
+	}
+	plan := &Plan{
+		Delete: []string{"obsolete"},
+		Create: []config.FeatureFlag{{Name: "fresh", Description: "new"}},
+		Update: []FlagUpdate{
+			{Flag: config.FeatureFlag{Name: "changed", Description: "after"}},
+			{Flag: config.FeatureFlag{Name: "broken", Description: "after"}},
+		},
+	}
+	failBrokenUpdate := func(r *http.Request) int {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/broken") {
+			return http.StatusBadRequest
+		}
+		return 0
+	}
+
+	t.Run("restores_snapshot", func(t *testing.T) {
+		gitlab := newFakeGitLab(t, initial...)
+		gitlab.fail = failBrokenUpdate
+		ffs := FeatureFlagService{Backend: gitlab.client(), ContinueOnError: true, RollbackOnFailure: true}
+
+		results, err := ffs.ApplyPlan(context.Background(), plan)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "update broken: failed with status 400")
+		assert.Contains(t, err.Error(), "all touched flags were rolled back")
+
+		var restored []string
+		for _, result := range results {
+			if result.Action == ActionRestore {
+				assert.NoError(t, result.Err)
+				restored = append(restored, result.Flag)
+			}
+		}
+		assert.ElementsMatch(t, []string{"obsolete", "fresh", "changed"}, restored, "rejected update of broken changed nothing")
+
+		flags := gitlab.snapshot()
+		assert.Len(t, flags, 3)
+		for _, flag := range initial {
+			assert.Equal(t, flag.Description, flags[flag.Name].Description)
+		}
+	})
+
+	t.Run("reports_unrestored_flags", func(t *testing.T) {
+		gitlab := newFakeGitLab(t, initial...)
+		gitlab.fail = func(r *http.Request) int {
+			// пересоздать удалённый флаг не получится
+			if r.Method == http.MethodPost && strings.Contains(peekBody(t, r), `"obsolete"`) {
+				return http.StatusInternalServerError
+			}
+			return failBrokenUpdate(r)
+		}
+		ffs := FeatureFlagService{Backend: gitlab.client(), ContinueOnError: true, RollbackOnFailure: true}
+
+		_, err := ffs.ApplyPlan(context.Background(), plan)
+
+		var rollbackErr *RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		require.Len(t, rollbackErr.Failures, 1)
+		assert.Equal(t, "obsolete", rollbackErr.Failures[0].Flag)
+		assert.NotContains(t, gitlab.snapshot(), "fresh")
+	})
+}
+
+// peekBody читает тело запроса, оставляя его доступным для обработчика
+func peekBody(t *testing.T, r *http.Request) string {
+	t.Helper()
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body)
+}
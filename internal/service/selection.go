@@ -0,0 +1,79 @@
+package service
+
+import (
+	"path"
+	"slices"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+// Selection ограничивает синхронизацию частью флагов.
+// Флаги вне выборки не создаются, не изменяются и не удаляются.
+type Selection struct {
+	// Only glob шаблоны имён, хотя бы одному из которых должен соответствовать флаг
+	Only []string
+	// Exclude glob шаблоны имён исключаемых флагов
+	Exclude []string
+	// Tags флаг должен иметь хотя бы один из тегов
+	Tags []string
+}
+
+func (s Selection) Empty() bool {
+	return len(s.Only) == 0 && len(s.Exclude) == 0 && len(s.Tags) == 0
+}
+
+// Includes проверяет флаг из конфигурации
+func (s Selection) Includes(flag config.FeatureFlag) bool {
+	if !s.includesName(flag.Name) {
+		return false
+	}
+	if len(s.Tags) == 0 {
+		return true
+	}
+	for _, tag := range flag.Tags {
+		if slices.Contains(s.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Selection) includesName(name string) bool {
+	if len(s.Only) > 0 && !matchAny(s.Only, name) {
+		return false
+	}
+	return !matchAny(s.Exclude, name)
+}
+
+// includesRemote проверяет флаг GitLab. У удалённых флагов нет тегов, поэтому при
+// фильтре по тегам выбираются только флаги, объявленные в конфигурации с нужным тегом.
+func (s Selection) includesRemote(name string, selectedDesired map[string]config.FeatureFlag) bool {
+	if len(s.Tags) > 0 {
+		_, selected := selectedDesired[name]
+		return selected
+	}
+	return s.includesName(name)
+}
+
+func (s Selection) filter(flags []config.FeatureFlag) []config.FeatureFlag {
+	if s.Empty() {
+		return flags
+	}
+	selected := make([]config.FeatureFlag, 0, len(flags))
+	for _, flag := range flags {
+		if s.Includes(flag) {
+			selected = append(selected, flag)
+		}
+	}
+	return selected
+}
+
+// matchAny шаблоны проверяются заранее при разборе аргументов, поэтому ошибка path.Match игнорируется
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectionIncludes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		selection Selection
+		flag      config.FeatureFlag
+		expected  bool
+	}{
+		{name: "empty selection", flag: config.FeatureFlag{Name: "any"}, expected: true},
+		{name: "only matches", selection: Selection{Only: []string{"team_a_*"}}, flag: config.FeatureFlag{Name: "team_a_checkout"}, expected: true},
+		{name: "only does not match", selection: Selection{Only: []string{"team_a_*"}}, flag: config.FeatureFlag{Name: "team_b_checkout"}, expected: false},
+		{name: "exclude wins over only", selection: Selection{Only: []string{"team_a_*"}, Exclude: []string{"*_legacy"}}, flag: config.FeatureFlag{Name: "team_a_legacy"}, expected: false},
+		{name: "tag matches", selection: Selection{Tags: []string{"payments"}}, flag: config.FeatureFlag{Name: "f", Tags: []string{"ui", "payments"}}, expected: true},
+		{name: "tag does not match", selection: Selection{Tags: []string{"payments"}}, flag: config.FeatureFlag{Name: "f", Tags: []string{"ui"}}, expected: false},
+		{name: "untagged flag with tag filter", selection: Selection{Tags: []string{"payments"}}, flag: config.FeatureFlag{Name: "f"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.selection.Includes(tc.flag))
+		})
+	}
+}
+
+func TestPlanFeatureFlagsSelection(t *testing.T) {
+	gitlab := newFakeGitLab(t,
+		config.FeatureFlag{Name: "team_a_old", Description: config.ManagedMarker},
+		config.FeatureFlag{Name: "team_b_old", Description: config.ManagedMarker},
+		config.FeatureFlag{Name: "team_a_tagged", Description: config.ManagedMarker},
+	)
+	desired := []config.FeatureFlag{
+		{Name: "team_a_new", Tags: []string{"a"}},
+		{Name: "team_b_new", Tags: []string{"b"}},
+		{Name: "team_a_tagged", Active: true, Tags: []string{"a"}},
+	}
+
+	t.Run("by_name", func(t *testing.T) {
+		ffs := FeatureFlagService{Backend: gitlab.client(), Prune: true, Selection: Selection{Only: []string{"team_a_*"}}}
+		plan, err := ffs.PlanFeatureFlags(context.Background(), desired)
+		require.NoError(t, err)
+
+		assert.Equal(t, []config.FeatureFlag{desired[0]}, plan.Create)
+		require.Len(t, plan.Update, 1)
+		assert.Equal(t, "team_a_tagged", plan.Update[0].Flag.Name)
+		assert.Equal(t, []string{"team_a_old"}, plan.Delete)
+		assert.Equal(t, 2, plan.RemoteCount)
+	})
+
+	t.Run("by_tag", func(t *testing.T) {
+		ffs := FeatureFlagService{Backend: gitlab.client(), Prune: true, Selection: Selection{Tags: []string{"a"}}}
+		plan, err := ffs.PlanFeatureFlags(context.Background(), desired)
+		require.NoError(t, err)
+
+		assert.Equal(t, []config.FeatureFlag{desired[0]}, plan.Create)
+		assert.Len(t, plan.Update, 1)
+		assert.Empty(t, plan.Delete, "remote flags without tags are outside a tag selection")
+		assert.Equal(t, 1, plan.RemoteCount)
+	})
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nkrus/gitlab-flagman/config"
+)
+
+const (
+	ActionCreateUserList Action = "create user list"
+	ActionUpdateUserList Action = "update user list"
+	ActionDeleteUserList Action = "delete user list"
+)
+
+// planUserLists дополняет план изменениями списков пользователей и проверяет, что списки,
+// на которые ссылаются стратегии, будут существовать. Списки, используемые флагами
+// в конфигурации или в GitLab, никогда не удаляются.
+func (ffs *FeatureFlagService) planUserLists(plan *Plan, flags, existingFlags []config.FeatureFlag, remoteLists []config.UserList) error {
+	referenced := referencedUserLists(flags)
+	if ffs.UserLists == nil && len(referenced) == 0 {
+		return nil
+	}
+
+	remote := make(map[string]config.UserList, len(remoteLists))
+	for _, list := range remoteLists {
+		remote[list.Name] = list
+	}
+
+	desired := make(map[string]bool, len(ffs.UserLists))
+	for _, list := range ffs.UserLists {
+		if desired[list.Name] {
+			return fmt.Errorf("user list %s is defined more than once", list.Name)
+		}
+		desired[list.Name] = true
+
+		remoteList, exists := remote[list.Name]
+		if !exists {
+			plan.CreateUserLists = append(plan.CreateUserLists, list)
+			continue
+		}
+		if r, d := formatUserXIDs(remoteList.UserXIDs), formatUserXIDs(list.UserXIDs); r != d {
+			plan.UpdateUserLists = append(plan.UpdateUserLists, UserListUpdate{
+				List:    list,
+				Changes: []FieldChange{{Field: "user_xids", Remote: r, Desired: d}},
+			})
+		}
+	}
+
+	names := make([]string, 0, len(referenced))
+	for name := range referenced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, exists := remote[name]; !exists && !desired[name] {
+			return fmt.Errorf("feature flag %s references user list %q, which exists neither in GitLab nor in the userLists section", referenced[name], name)
+		}
+	}
+
+	plan.RemoteUserListCount = len(remoteLists)
+
+	// Лишние списки удаляются только при полной синхронизации с -prune-user-lists
+	if ffs.UserLists != nil && ffs.PruneUserLists && ffs.Selection.Empty() {
+		inUse := referencedUserLists(existingFlags)
+		for name := range referenced {
+			inUse[name] = referenced[name]
+		}
+		for _, list := range remoteLists {
+			if desired[list.Name] {
+				continue
+			}
+			if flag, used := inUse[list.Name]; used {
+				log.Printf("User list %s is not in config but is used by feature flag %s, kept", list.Name, flag)
+				continue
+			}
+			plan.DeleteUserLists = append(plan.DeleteUserLists, list.Name)
+		}
+	}
+
+	log.Printf("User lists to add: %d, to update: %d, to delete: %d", len(plan.CreateUserLists), len(plan.UpdateUserLists), len(plan.DeleteUserLists))
+	return nil
+}
+
+// referencedUserLists возвращает имена списков пользователей, на которые ссылаются стратегии,
+// вместе с именем первого ссылающегося флага
+func referencedUserLists(flags []config.FeatureFlag) map[string]string {
+	referenced := make(map[string]string)
+	for _, flag := range flags {
+		for _, strategy := range flag.Strategies {
+			if _, seen := referenced[strategy.UserList]; strategy.UserList != "" && !seen {
+				referenced[strategy.UserList] = flag.Name
+			}
+		}
+	}
+	return referenced
+}
+
+// formatUserXIDs каноническая форма пользователей списка: порядок и повторы не важны
+func formatUserXIDs(xids []string) string {
+	normalized := make([]string, 0, len(xids))
+	for _, xid := range xids {
+		if xid = strings.TrimSpace(xid); xid != "" {
+			normalized = append(normalized, xid)
+		}
+	}
+	sort.Strings(normalized)
+	return strings.Join(slices.Compact(normalized), ", ")
+}
+
+func userListName(list config.UserList) string {
+	return list.Name
+}
+
+func userListUpdateName(update UserListUpdate) string {
+	return update.List.Name
+}
+
+func (ffs *FeatureFlagService) updateUserList(ctx context.Context, update UserListUpdate) error {
+	return ffs.Backend.UpdateUserList(ctx, update.List)
+}
+
+func isUserListAction(action Action) bool {
+	return action == ActionCreateUserList || action == ActionUpdateUserList || action == ActionDeleteUserList
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/nkrus/gitlab-flagman/config"
+	"github.com/nkrus/gitlab-flagman/internal/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userListFlag(name, list string) config.FeatureFlag {
+	return config.FeatureFlag{Name: name, Active: true, Strategies: []config.Strategy{
+		{Name: "gitlabUserList", Parameters: map[string]interface{}{}, Scopes: []config.Scope{{Environment: "*"}}, UserList: list},
+	}}
+}
+
+func TestSyncUserLists(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Creates lists before flags that reference them", func(t *testing.T) {
+		memory := backend.NewMemory()
+		ffs := FeatureFlagService{Backend: memory, UserLists: []config.UserList{{Name: "beta", UserXIDs: []string{"alice"}}}}
+
+		require.NoError(t, ffs.SyncFeatureFlags([]config.FeatureFlag{userListFlag("flag1", "beta")}))
+
+		flag, err := memory.GetFeatureFlag(ctx, "flag1")
+		require.NoError(t, err)
+		assert.Equal(t, "beta", flag.Strategies[0].UserList)
+		lists, err := memory.GetAllUserLists(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []config.UserList{{Name: "beta", UserXIDs: []string{"alice"}}}, lists)
+	})
+
+	t.Run("Updates users regardless of order", func(t *testing.T) {
+		memory := backend.NewMemory().AddUserLists(
+			config.UserList{Name: "same", UserXIDs: []string{"bob", "alice"}},
+			config.UserList{Name: "changed", UserXIDs: []string{"alice"}},
+		)
+		ffs := FeatureFlagService{Backend: memory, UserLists: []config.UserList{
+			{Name: "same", UserXIDs: []string{"alice", "bob"}},
+			{Name: "changed", UserXIDs: []string{"alice", "carol"}},
+		}}
+
+		plan, err := ffs.PlanFeatureFlags(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []UserListUpdate{{
+			List:    config.UserList{Name: "changed", UserXIDs: []string{"alice", "carol"}},
+			Changes: []FieldChange{{Field: "user_xids", Remote: "alice", Desired: "alice, carol"}},
+		}}, plan.UpdateUserLists)
+		assert.Equal(t, 1, plan.Len())
+
+		var out bytes.Buffer
+		require.NoError(t, plan.WriteReport(&out))
+		assert.Contains(t, out.String(), "User lists: 0 to create, 1 to update, 0 to delete")
+		assert.Contains(t, out.String(), "~ user list changed\n    user_xids: alice -> alice, carol")
+	})
+
+	t.Run("Prune keeps lists that are still referenced", func(t *testing.T) {
+		manual := userListFlag("manual", "used_by_manual")
+		memory := backend.NewMemory().AddUserLists(
+			config.UserList{Name: "unused"},
+			config.UserList{Name: "used_by_manual"},
+			config.UserList{Name: "used_by_config"},
+		)
+		require.NoError(t, memory.CreateFeatureFlag(ctx, manual))
+		ffs := FeatureFlagService{Backend: memory, PruneUserLists: true, UserLists: []config.UserList{}}
+
+		require.NoError(t, ffs.SyncFeatureFlags([]config.FeatureFlag{userListFlag("flag1", "used_by_config")}))
+
+		lists, err := memory.GetAllUserLists(ctx)
+		require.NoError(t, err)
+		names := make([]string, 0, len(lists))
+		for _, list := range lists {
+			names = append(names, list.Name)
+		}
+		assert.Equal(t, []string{"used_by_config", "used_by_manual"}, names)
+	})
+
+	t.Run("Prune of flags keeps lists", func(t *testing.T) {
+		memory := backend.NewMemory().AddUserLists(config.UserList{Name: "made_by_hand"})
+		ffs := FeatureFlagService{Backend: memory, Prune: true, UserLists: []config.UserList{}}
+
+		plan, err := ffs.PlanFeatureFlags(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, plan.DeleteUserLists)
+	})
+
+	t.Run("List deletions count against the deletion limit", func(t *testing.T) {
+		memory := backend.NewMemory().AddUserLists(config.UserList{Name: "a"}, config.UserList{Name: "b"}, config.UserList{Name: "c"})
+		limit := DeletionLimit{Value: 50, Percent: true}
+		ffs := FeatureFlagService{Backend: memory, PruneUserLists: true, MaxDeletes: &limit, UserLists: []config.UserList{}}
+
+		err := ffs.SyncFeatureFlags(nil)
+		assert.EqualError(t, err, "refusing to delete 3 of 3 remote user lists (limit 50% allows 1), pass -force to delete anyway: a, b, c")
+		lists, err := memory.GetAllUserLists(ctx)
+		require.NoError(t, err)
+		assert.Len(t, lists, 3)
+	})
+
+	t.Run("Lists are not managed without userLists section", func(t *testing.T) {
+		memory := backend.NewMemory().AddUserLists(config.UserList{Name: "unused"})
+		ffs := FeatureFlagService{Backend: memory, Prune: true, PruneUserLists: true}
+
+		plan, err := ffs.PlanFeatureFlags(ctx, nil)
+		require.NoError(t, err)
+		assert.True(t, plan.Empty())
+	})
+
+	t.Run("Unknown list", func(t *testing.T) {
+		ffs := FeatureFlagService{Backend: backend.NewMemory()}
+
+		_, err := ffs.PlanFeatureFlags(ctx, []config.FeatureFlag{userListFlag("flag1", "missing")})
+		assert.ErrorContains(t, err, `feature flag flag1 references user list "missing"`)
+	})
+
+	t.Run("Duplicate list", func(t *testing.T) {
+		ffs := FeatureFlagService{Backend: backend.NewMemory(), UserLists: []config.UserList{{Name: "beta"}, {Name: "beta"}}}
+
+		_, err := ffs.PlanFeatureFlags(ctx, nil)
+		assert.ErrorContains(t, err, "user list beta is defined more than once")
+	})
+}